@@ -0,0 +1,142 @@
+package main
+
+import (
+	"article-versioning-api/config"
+	"article-versioning-api/core/entity"
+	"article-versioning-api/core/usecase"
+	articlerepository "article-versioning-api/repository/article"
+	tagrepository "article-versioning-api/repository/tag"
+	tokenrepository "article-versioning-api/repository/token"
+	userrepository "article-versioning-api/repository/user"
+	transactionutil "article-versioning-api/utils/transaction"
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	_ "github.com/lib/pq"
+)
+
+type command struct {
+	name        string
+	description string
+	run         func(u *usecases, args []string) error
+}
+
+type usecases struct {
+	tagUsecase  usecase.TagUsecaseInterface
+	userUsecase usecase.UserUsecaseInterface
+}
+
+var commands = []command{
+	// recount tag usage and pair stats when they drift from the published versions
+	{
+		name:        "rebuild-tag-stats",
+		description: "rebuild tag_stats and tag_pair_stats from the published versions",
+		run: func(u *usecases, args []string) error {
+			return u.tagUsecase.RebuildTagStats()
+		},
+	},
+	// bootstrap a new installation, other admins are invited by an admin
+	{
+		name:        "create-admin",
+		description: "create the first admin: create-admin <username>, password from ADMIN_PASSWORD or stdin",
+		run: func(u *usecases, args []string) error {
+			if len(args) != 1 {
+				return errors.New("username is mandatory")
+			}
+
+			password, err := readPassword()
+			if err != nil {
+				return err
+			}
+
+			return u.userUsecase.CreateFirstAdmin(&entity.RegisterUserRequest{
+				Username: args[0],
+				Password: password,
+			})
+		},
+	},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(2)
+	}
+
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == os.Args[1] {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		printUsage()
+		os.Exit(2)
+	}
+
+	cfg := config.GetConfig()
+
+	db, err := sql.Open("postgres", cfg.DatabaseUrl)
+	if err != nil {
+		log.Fatalf("error open database: %v", err.Error())
+	}
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	if err != nil {
+		log.Fatalf("error open database: %v", err.Error())
+	}
+
+	transactionPkg := transactionutil.NewConnection(gormDB)
+
+	articleRepo := articlerepository.NewArticleRepository(db, cfg, gormDB)
+	tagRepo := tagrepository.NewTagRepository(db, cfg, gormDB)
+	userRepo := userrepository.NewUserRepository(db, cfg, gormDB)
+	tokenRepo := tokenrepository.NewTokenRepository(cfg, gormDB)
+
+	// commands do not issue tokens, so no signing keys are loaded
+	authUsecase := usecase.NewAuthUsecase(userRepo, tokenRepo, nil, cfg)
+
+	u := &usecases{
+		tagUsecase:  usecase.NewTagUsecase(tagRepo, articleRepo, transactionPkg, cfg),
+		userUsecase: usecase.NewUserUsecase(userRepo, tokenRepo, authUsecase, transactionPkg, cfg),
+	}
+
+	err = cmd.run(u, os.Args[2:])
+	if err != nil {
+		log.Fatalf("error command %s: %v", cmd.name, err.Error())
+	}
+
+	log.Printf("[info] command %s is successful", cmd.name)
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "usage: admin <command> [arguments]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-20s %s\n", c.name, c.description)
+	}
+}
+
+// read the password from ADMIN_PASSWORD, or the first line of stdin so it is not in the shell history
+func readPassword() (string, error) {
+	if password := os.Getenv("ADMIN_PASSWORD"); password != "" {
+		return password, nil
+	}
+
+	fmt.Fprint(os.Stderr, "password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("error read password: %s", err.Error())
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -6,10 +6,16 @@ import (
 	"article-versioning-api/handler"
 	articlerepository "article-versioning-api/repository/article"
 	tagrepository "article-versioning-api/repository/tag"
+	tokenrepository "article-versioning-api/repository/token"
 	userrepository "article-versioning-api/repository/user"
+	jwkutil "article-versioning-api/utils/jwk"
 	transactionutil "article-versioning-api/utils/transaction"
 	"database/sql"
+	"errors"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/driver/postgres"
@@ -41,26 +47,45 @@ func main() {
 
 	transactionPkg := transactionutil.NewConnection(gormDB)
 
-	userRepo := userrepository.NewUserRepository(db, cfg)
+	userRepo := userrepository.NewUserRepository(db, cfg, gormDB)
 	articleRepo := articlerepository.NewArticleRepository(db, cfg, gormDB)
-	tagRepo := tagrepository.NewTagRepository(db, gormDB)
+	tagRepo := tagrepository.NewTagRepository(db, cfg, gormDB)
+	tokenRepo := tokenrepository.NewTokenRepository(cfg, gormDB)
 
-	authUsecase := usecase.NewAuthUsecase(cfg)
-	userUsecase := usecase.NewUserUsecase(userRepo, authUsecase)
+	// tokens are signed with the keys of TOKEN_KEY_DIR, keys are reloaded on SIGHUP to rotate them
+	if cfg.TokenKeyDir == "" {
+		panic(errors.New("TOKEN_KEY_DIR is mandatory, tokens are only signed with the keys of the directory"))
+	}
+	keySet, err := jwkutil.NewKeySet(cfg.TokenKeyDir, cfg.TokenSigningKeyID)
+	if err != nil {
+		panic(err)
+	}
+	go reloadKeySetOnHangup(keySet)
+
+	authUsecase := usecase.NewAuthUsecase(userRepo, tokenRepo, keySet, cfg)
+	userUsecase := usecase.NewUserUsecase(userRepo, tokenRepo, authUsecase, transactionPkg, cfg)
 	articleUsecase := usecase.NewArticleUsecase(articleRepo, tagRepo, transactionPkg, cfg)
-	tagUsecase := usecase.NewTagUsecase(tagRepo, transactionPkg, cfg)
+	tagUsecase := usecase.NewTagUsecase(tagRepo, articleRepo, transactionPkg, cfg)
 
 	userHandler := handler.NewUserHandler(userUsecase)
 	authHandler := handler.NewAuthHandler(authUsecase)
 	articleHandler := handler.NewArticleHandler(articleUsecase)
 	tagHandler := handler.NewTagHandler(tagUsecase)
 
+	authenticatedRoute := router.Group("/")
+	authenticatedRoute.Use(authHandler.VerifyToken)
+	{
+		authenticatedRoute.POST("/users/logout", userHandler.Logout)
+	}
+
 	writerRoute := router.Group("/")
 	writerRoute.Use(authHandler.VerifyToken)
 	writerRoute.Use(authHandler.VerifyRole([]string{"writer"}))
 	{
 		writerRoute.POST("/articles", articleHandler.CreateArticle)
 		writerRoute.POST("/articles/:serial/version", articleHandler.CreateArticleVersion)
+		writerRoute.POST("/articles/:serial/versions/:versionSerial/restore", articleHandler.RestoreArticleVersion)
+		writerRoute.POST("/articles/:serial/merge", articleHandler.MergeArticleVersions)
 	}
 
 	adminWriterRoute := router.Group("/")
@@ -68,26 +93,95 @@ func main() {
 	adminWriterRoute.Use(authHandler.VerifyRole([]string{"admin", "editor", "writer"}))
 	{
 		adminWriterRoute.PATCH("articles/:serial/versions/:versionSerial/status", articleHandler.UpdateArticleVersionStatus)
+		adminWriterRoute.PUT("/articles/:serial/versions/:versionSerial/tags", articleHandler.UpdateVersionTags)
 		adminWriterRoute.DELETE("articles/:serial", articleHandler.DeleteArticle)
 		adminWriterRoute.GET("/articles/:serial/latest-details", articleHandler.GetArticleLatestDetail)
 		adminWriterRoute.GET("/articles/:serial/versions", articleHandler.GetVersionsByArticleSerial)
+		adminWriterRoute.GET("/articles/:serial/timeline", articleHandler.GetArticleTimeline)
 		adminWriterRoute.GET("/articles/versions/:versionSerial", articleHandler.GetVersionBySerial)
+		adminWriterRoute.GET("/articles/:serial/versions/:versionSerial/diff/:targetVersionSerial", articleHandler.GetVersionDiff)
+		adminWriterRoute.GET("/articles/:serial/versions/:versionSerial/reviews", articleHandler.GetVersionReviews)
+		adminWriterRoute.POST("/articles/:serial/versions/:versionSerial/schedules", articleHandler.ScheduleArticleVersion)
+		adminWriterRoute.GET("/articles/:serial/schedules", articleHandler.GetVersionSchedules)
+		adminWriterRoute.POST("/articles/suggest-tags", articleHandler.SuggestTags)
+		adminWriterRoute.DELETE("/articles/:serial/schedules/:scheduleSerial", articleHandler.CancelVersionSchedule)
+		adminWriterRoute.GET("/articles/:serial/coauthors", articleHandler.GetArticleCoauthors)
+		adminWriterRoute.POST("/articles/:serial/coauthors", articleHandler.AddArticleCoauthor)
+		adminWriterRoute.DELETE("/articles/:serial/coauthors/:username", articleHandler.RemoveArticleCoauthor)
 
 		adminWriterRoute.POST("/tags", tagHandler.CreateTag)
 		adminWriterRoute.GET("/tags", tagHandler.GetTags)
+		adminWriterRoute.GET("/tags/trending", tagHandler.GetTrendingTags)
+		adminWriterRoute.GET("/tags/graph", tagHandler.GetTagGraph)
 		adminWriterRoute.GET("/tags/:serial", tagHandler.GetTagBySerial)
+		adminWriterRoute.GET("/tags/:serial/trend", tagHandler.GetTagTrend)
+		adminWriterRoute.GET("/tags/:serial/children", tagHandler.GetTagChildren)
+		adminWriterRoute.GET("/tags/:serial/synonyms", tagHandler.GetTagSynonyms)
+	}
+
+	adminEditorRoute := router.Group("/")
+	adminEditorRoute.Use(authHandler.VerifyToken)
+	adminEditorRoute.Use(authHandler.VerifyRole([]string{"admin", "editor"}))
+	{
+		adminEditorRoute.PATCH("/tags/:serial/parent", tagHandler.UpdateTagParent)
+		adminEditorRoute.PATCH("/tags/:serial", tagHandler.RenameTag)
+		adminEditorRoute.DELETE("/tags/:serial", tagHandler.DeleteTag)
+		adminEditorRoute.POST("/tags/:serial/merge", tagHandler.MergeTag)
+		adminEditorRoute.POST("/tags/:serial/synonyms", tagHandler.CreateTagSynonym)
+		adminEditorRoute.DELETE("/tags/:serial/synonyms/:name", tagHandler.DeleteTagSynonym)
+	}
+
+	adminRoute := router.Group("/")
+	adminRoute.Use(authHandler.VerifyToken)
+	adminRoute.Use(authHandler.VerifyRole([]string{"admin"}))
+	{
+		adminRoute.POST("/articles/:serial/restore", articleHandler.RestoreDeletedArticle)
+
+		adminRoute.GET("/users", userHandler.GetUsers)
+		adminRoute.POST("/users/invitations", userHandler.CreateInvitation)
+		adminRoute.PATCH("/users/:username/role", userHandler.UpdateUserRole)
+		adminRoute.PATCH("/users/:username/status", userHandler.UpdateUserStatus)
+		adminRoute.POST("/users/:username/password-reset", userHandler.ForcePasswordReset)
+		adminRoute.GET("/users/:username/audit-logs", userHandler.GetUserAuditLogs)
+	}
+
+	workerRoute := router.Group("/")
+	workerRoute.Use(authHandler.VerifyWorkerSecret)
+	{
+		workerRoute.DELETE("/articles/purge", articleHandler.PurgeDeletedArticles)
+		workerRoute.PUT("/articles/versions/lexeme-stats", articleHandler.UpdateLexemeStats)
+		workerRoute.PUT("/articles/versions/schedules", articleHandler.ApplyDueVersionSchedules)
+		workerRoute.PUT("/articles/versions/tag-relationship-scores", articleHandler.UpdateTagRelationshipScores)
+		workerRoute.DELETE("/users/tokens/expired", userHandler.PurgeExpiredTokens)
 	}
 
 	NonAuthenticatedRoute := router.Group("/")
 	NonAuthenticatedRoute.Use(authHandler.VerifyNotMandatoryToken)
 	{
 		NonAuthenticatedRoute.GET("/articles", articleHandler.GetArticles)
+		NonAuthenticatedRoute.GET("/articles/:serial/related", articleHandler.GetRelatedArticles)
 	}
 
 	router.POST("/users/register", userHandler.RegisterUser)
 	router.POST("/users/login", userHandler.Login)
+	router.POST("/users/refresh", userHandler.RefreshToken)
+	router.PUT("/users/password", userHandler.ResetPassword)
+	router.GET("/.well-known/jwks.json", authHandler.GetJWKS)
 
 	router.PUT("/tags/trending-score", articleHandler.UpdateTrendingScoreTags)
 
 	router.Run()
 }
+
+func reloadKeySetOnHangup(keySet *jwkutil.KeySet) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	for range hangup {
+		err := keySet.Reload()
+		if err != nil {
+			log.Printf("[error] error reload token keys: %v", err.Error())
+			continue
+		}
+		log.Printf("[info] token keys are reloaded, signing key is %s", keySet.SigningKey().ID)
+	}
+}
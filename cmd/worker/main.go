@@ -14,23 +14,82 @@ import (
 	_ "github.com/lib/pq"
 )
 
-func main() {
-	// cron job update tag trending score value (using exponential decay)
-	cronJobSchedule := os.Getenv("UPDATE_TAG_TRENDING_SCORE_SCHEDULE")
+const (
+	appURL             = "http://app:8080"
+	workerSecretHeader = "X-Worker-Secret"
+)
+
+type job struct {
+	name        string
+	scheduleEnv string
+	method      string
+	path        string
+}
+
+var jobs = []job{
+	// update tag trending score value (using exponential decay)
+	{
+		name:        "update tag trending score",
+		scheduleEnv: "UPDATE_TAG_TRENDING_SCORE_SCHEDULE",
+		method:      http.MethodPut,
+		path:        "/tags/trending-score",
+	},
+	// recalculate tag relationship score of all published versions (PMI changes as tag stats change) (needs WORKER_SECRET)
+	{
+		name:        "update tag relationship score",
+		scheduleEnv: "UPDATE_TAG_RELATIONSHIP_SCORE_SCHEDULE",
+		method:      http.MethodPut,
+		path:        "/articles/versions/tag-relationship-scores",
+	},
+	// publish and unpublish versions that are scheduled and due (needs WORKER_SECRET)
+	{
+		name:        "apply version schedule",
+		scheduleEnv: "APPLY_VERSION_SCHEDULE_SCHEDULE",
+		method:      http.MethodPut,
+		path:        "/articles/versions/schedules",
+	},
+	// recount the lexemes of published versions used by tag suggestions (needs WORKER_SECRET)
+	{
+		name:        "update lexeme stats",
+		scheduleEnv: "UPDATE_LEXEME_STATS_SCHEDULE",
+		method:      http.MethodPut,
+		path:        "/articles/versions/lexeme-stats",
+	},
+	// hard delete articles that have been soft deleted longer than the retention period (needs WORKER_SECRET)
+	{
+		name:        "purge deleted article",
+		scheduleEnv: "PURGE_DELETED_ARTICLE_SCHEDULE",
+		method:      http.MethodDelete,
+		path:        "/articles/purge",
+	},
+	// delete refresh tokens and revoked access tokens that are expired (needs WORKER_SECRET)
+	{
+		name:        "purge expired token",
+		scheduleEnv: "PURGE_EXPIRED_TOKEN_SCHEDULE",
+		method:      http.MethodDelete,
+		path:        "/users/tokens/expired",
+	},
+}
 
+func main() {
 	c := cron.New()
-	_, err := c.AddFunc(cronJobSchedule, func() {
-		err := FetchAndStoreData()
+
+	for _, j := range jobs {
+		cronJobSchedule := os.Getenv(j.scheduleEnv)
+
+		_, err := c.AddFunc(cronJobSchedule, func() {
+			err := callApp(j)
+			if err != nil {
+				log.Printf("error cron job %s: %v", j.name, err.Error())
+			}
+		})
 		if err != nil {
-			log.Printf("error cron job update tag trending score: %v", err.Error())
+			log.Fatalf("error cron job %s: %v", j.name, err.Error())
 		}
-	})
-	if err != nil {
-		log.Fatalf("error cron job update tag trending score: %v", err.Error())
 	}
 
 	c.Start()
-	log.Println("[info] start cron job update tag trending score")
+	log.Println("[info] start cron jobs")
 
 	// graceful shutdown
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
@@ -38,18 +97,17 @@ func main() {
 
 	<-ctx.Done()
 
-	log.Println("[info] shutting down cron job update tag trending score")
+	log.Println("[info] shutting down cron jobs")
 	c.Stop()
-	log.Println("[info] cron job update tag trending score stopped")
+	log.Println("[info] cron jobs stopped")
 }
 
-func FetchAndStoreData() error {
-	clientURL := "http://app:8080/tags/trending-score"
-
-	req, err := http.NewRequest(http.MethodPut, clientURL, nil)
+func callApp(j job) error {
+	req, err := http.NewRequest(j.method, appURL+j.path, nil)
 	if err != nil {
 		return err
 	}
+	req.Header.Set(workerSecretHeader, os.Getenv("WORKER_SECRET"))
 
 	client := &http.Client{}
 	response, err := client.Do(req)
@@ -62,6 +120,6 @@ func FetchAndStoreData() error {
 		return fmt.Errorf("error with status code: %v", response.StatusCode)
 	}
 
-	log.Printf("[info] cron job update tag trending score is successful")
+	log.Printf("[info] cron job %s is successful", j.name)
 	return nil
 }
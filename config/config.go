@@ -3,16 +3,30 @@ package config
 import (
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 )
 
 type Config struct {
-	TokenSecret                  string  `envconfig:"TOKEN_SECRET" default:"token_secret"`
-	PSQLUniqueViolationErrorCode string  `envconfig:"PSQL_UNIQUE_VIOLATION_ERROR_CODE" default:"23505"`
-	PSQLNotFoundErrorCode        string  `envconfig:"PSQL_NOT_FOUND_ERROR_CODE" default:"20000"`
-	DatabaseUrl                  string  `envconfig:"DATABASE_URL" default:"host=localhost port=5432 user=postgres password=postgres dbname=database sslmode=disable"`
-	TrendingScoreHalLifeDays     float32 `envconfig:"TRENDING_SCORE_HALF_LIFE_DAYS" default:"7"`
+	PSQLUniqueViolationErrorCode    string        `envconfig:"PSQL_UNIQUE_VIOLATION_ERROR_CODE" default:"23505"`
+	PSQLNotFoundErrorCode           string        `envconfig:"PSQL_NOT_FOUND_ERROR_CODE" default:"20000"`
+	PSQLForeignKeyErrorCode         string        `envconfig:"PSQL_FOREIGN_KEY_VIOLATION_ERROR_CODE" default:"23503"`
+	DatabaseUrl                     string        `envconfig:"DATABASE_URL" default:"host=localhost port=5432 user=postgres password=postgres dbname=database sslmode=disable"`
+	TrendingScoreHalLifeDays        float32       `envconfig:"TRENDING_SCORE_HALF_LIFE_DAYS" default:"7"`
+	DeletedArticleRetentionDays     int           `envconfig:"DELETED_ARTICLE_RETENTION_DAYS" default:"30"`
+	TagSnapshotRetentionDays        int           `envconfig:"TAG_TRENDING_SNAPSHOT_RETENTION_DAYS" default:"365"`
+	RelatedArticleMinScore          float32       `envconfig:"RELATED_ARTICLE_MIN_SCORE" default:"0.5"`
+	TagSuggestionCooccurrenceWeight float32       `envconfig:"TAG_SUGGESTION_COOCCURRENCE_WEIGHT" default:"0.3"`
+	MaxTagsPerVersion               int           `envconfig:"MAX_TAGS_PER_VERSION" default:"10"`
+	MaxDiffLines                    int           `envconfig:"MAX_DIFF_LINES" default:"5000"`
+	AccessTokenTTL                  time.Duration `envconfig:"ACCESS_TOKEN_TTL" default:"1h"`
+	RefreshTokenTTL                 time.Duration `envconfig:"REFRESH_TOKEN_TTL" default:"720h"`
+	TokenKeyDir                     string        `envconfig:"TOKEN_KEY_DIR"`
+	TokenSigningKeyID               string        `envconfig:"TOKEN_SIGNING_KEY_ID"`
+	PublicRegistrationEnabled       bool          `envconfig:"PUBLIC_REGISTRATION_ENABLED" default:"true"`
+	InvitationTTL                   time.Duration `envconfig:"INVITATION_TTL" default:"168h"`
+	WorkerSecret                    string        `envconfig:"WORKER_SECRET"`
 }
 
 var config *Config
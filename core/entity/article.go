@@ -1,7 +1,9 @@
 package entity
 
 import (
+	diffutil "article-versioning-api/utils/diff"
 	errorutil "article-versioning-api/utils/error"
+	"fmt"
 	"time"
 
 	"github.com/pkg/errors"
@@ -12,6 +14,7 @@ const (
 	SortByUpdatedAt            = "updated_at"
 	SortByPublishedAt          = "published_at"
 	SortByTagRelationshipScore = "tag_relationship_score"
+	SortByRelevance            = "relevance"
 
 	SortTypeAsc  = "asc"
 	SortTypeDesc = "desc"
@@ -42,22 +45,74 @@ const (
 	VersionStatusPublished
 	VersionStatusArchived
 	VersionStatusDeleted
+	VersionStatusInReview
+	VersionStatusChangesRequested
+	VersionStatusApproved
 )
 
 var (
 	mapVersionStatusToString = map[VersionStatus]string{
-		VersionStatusUnknown:   "unknown",
-		VersionStatusDraft:     "draft",
-		VersionStatusPublished: "published",
-		VersionStatusArchived:  "archived",
-		VersionStatusDeleted:   "deleted",
+		VersionStatusUnknown:          "unknown",
+		VersionStatusDraft:            "draft",
+		VersionStatusPublished:        "published",
+		VersionStatusArchived:         "archived",
+		VersionStatusDeleted:          "deleted",
+		VersionStatusInReview:         "in_review",
+		VersionStatusChangesRequested: "changes_requested",
+		VersionStatusApproved:         "approved",
 	}
 	mapStringToVersionStatus = map[string]VersionStatus{
-		"unknown":   VersionStatusUnknown,
-		"draft":     VersionStatusDraft,
-		"published": VersionStatusPublished,
-		"archived":  VersionStatusArchived,
-		"deleted":   VersionStatusDeleted,
+		"unknown":           VersionStatusUnknown,
+		"draft":             VersionStatusDraft,
+		"published":         VersionStatusPublished,
+		"archived":          VersionStatusArchived,
+		"deleted":           VersionStatusDeleted,
+		"in_review":         VersionStatusInReview,
+		"changes_requested": VersionStatusChangesRequested,
+		"approved":          VersionStatusApproved,
+	}
+)
+
+var (
+	contributorRoles = []UserRole{UserRoleWriter, UserRoleEditor, UserRoleAdmin}
+	reviewerRoles    = []UserRole{UserRoleEditor, UserRoleAdmin}
+
+	// allowed version status transitions and the roles that can do them,
+	// deleted status is only set by deleting the article
+	mapVersionStatusTransitionRoles = map[VersionStatus]map[VersionStatus][]UserRole{
+		VersionStatusDraft: {
+			VersionStatusInReview: contributorRoles,
+			VersionStatusArchived: contributorRoles,
+		},
+		VersionStatusInReview: {
+			VersionStatusApproved:         reviewerRoles,
+			VersionStatusChangesRequested: reviewerRoles,
+			VersionStatusDraft:            contributorRoles,
+		},
+		VersionStatusChangesRequested: {
+			VersionStatusInReview: contributorRoles,
+			VersionStatusDraft:    contributorRoles,
+			VersionStatusArchived: contributorRoles,
+		},
+		VersionStatusApproved: {
+			VersionStatusPublished: reviewerRoles,
+			VersionStatusDraft:     contributorRoles,
+			VersionStatusArchived:  reviewerRoles,
+		},
+		VersionStatusPublished: {
+			VersionStatusDraft:    reviewerRoles,
+			VersionStatusArchived: reviewerRoles,
+		},
+		VersionStatusArchived: {
+			VersionStatusDraft: contributorRoles,
+		},
+	}
+
+	// transitions that are recorded as review decision
+	reviewVersionStatuses = map[VersionStatus]bool{
+		VersionStatusInReview:         true,
+		VersionStatusChangesRequested: true,
+		VersionStatusApproved:         true,
 	}
 )
 
@@ -78,20 +133,81 @@ func IsPublishedStatus(status string) bool {
 	return status == VersionStatusPublished.String()
 }
 
+func IsReviewStatus(status string) bool {
+	return reviewVersionStatuses[StringToVersionRole(status)]
+}
+
+// ValidateVersionStatusTransition checks the transition is allowed and the role is able to do it,
+// empty role is used by the system (e.g. scheduled publishing) that can do any allowed transition
+func ValidateVersionStatusTransition(currStatus, newStatus, role string) error {
+	roles, ok := mapVersionStatusTransitionRoles[StringToVersionRole(currStatus)][StringToVersionRole(newStatus)]
+	if !ok {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error version status transition: status '%s' can not be changed to '%s'", currStatus, newStatus))
+	}
+	if role == "" {
+		return nil
+	}
+
+	for _, r := range roles {
+		if r.String() == role {
+			return nil
+		}
+	}
+
+	return errorutil.NewCustomError(errorutil.ErrForbidden, fmt.Errorf("error version status transition: role '%s' can not change status '%s' to '%s'", role, currStatus, newStatus))
+}
+
+// IsArticleModeratorRole returns true if the role can change every article,
+// other roles can only change articles they own or co-author
+func IsArticleModeratorRole(role string) bool {
+	for _, r := range reviewerRoles {
+		if r.String() == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateVersionTagsEditable checks the tags of a version in the status can be replaced by the role,
+// draft tags can be edited by contributors and the published tags only by reviewers
+func ValidateVersionTagsEditable(status, role string) error {
+	var roles []UserRole
+	switch StringToVersionRole(status) {
+	case VersionStatusDraft:
+		roles = contributorRoles
+	case VersionStatusPublished:
+		roles = reviewerRoles
+	default:
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error edit version tags: tags of version with status '%s' can not be edited", status))
+	}
+
+	for _, r := range roles {
+		if r.String() == role {
+			return nil
+		}
+	}
+
+	return errorutil.NewCustomError(errorutil.ErrForbidden, fmt.Errorf("error edit version tags: role '%s' can not edit tags of version with status '%s'", role, status))
+}
+
 type Version struct {
-	Serial               string     `json:"serial"`
-	AuthorUsername       string     `json:"authorUsername"`
-	VersionNumber        int        `json:"versionNumber"`
-	ArticleSerial        string     `json:"articleSerial"`
-	Title                string     `json:"title"`
-	Content              string     `json:"content"`
-	Status               string     `json:"status"`
-	CreatedAt            time.Time  `json:"createdAt"`
-	UpdatedAt            *time.Time `json:"updatedAt"`
-	DeletedAt            *time.Time `json:"deletedAt"`
-	PublishedAt          *time.Time `json:"publishedAt"`
-	TagRelationshipScore float32    `json:"tagRelationshipScore"`
-	Tags                 []*Tag     `json:"tags"`
+	Serial                    string     `json:"serial"`
+	AuthorUsername            string     `json:"authorUsername"`
+	VersionNumber             int        `json:"versionNumber"`
+	ArticleSerial             string     `json:"articleSerial"`
+	Title                     string     `json:"title"`
+	Content                   string     `json:"content"`
+	Status                    string     `json:"status"`
+	CreatedAt                 time.Time  `json:"createdAt"`
+	UpdatedAt                 *time.Time `json:"updatedAt"`
+	DeletedAt                 *time.Time `json:"deletedAt"`
+	PublishedAt               *time.Time `json:"publishedAt"`
+	TagRelationshipScore      float32    `json:"tagRelationshipScore"`
+	RestoredFromVersionSerial *string    `json:"restoredFromVersionSerial"`
+	SearchRank                float32    `json:"searchRank,omitempty"` // only filled on full-text search
+	Snippet                   string     `json:"snippet,omitempty"`    // only filled on full-text search, matches are wrapped in <mark></mark>
+	Tags                      []*Tag     `json:"tags"`
 }
 
 func (v *Version) TagSerials() []string {
@@ -119,8 +235,46 @@ type CreateArticleResponse struct {
 }
 
 type Article struct {
-	Serial   string
-	Versions []*Version
+	Serial        string
+	OwnerUsername string // creator of the article
+	CreatedAt     time.Time
+	UpdatedAt     *time.Time
+	DeletedAt     *time.Time
+	Versions      []*Version
+}
+
+// ArticleCoauthor can version and submit the article like its owner
+type ArticleCoauthor struct {
+	Username        string    `json:"username"`
+	AddedByUsername string    `json:"addedByUsername"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+type AddArticleCoauthorRequest struct {
+	ArticleSerial string `json:"-" form:"-"`
+	Username      string
+	ActorUsername string `json:"-" form:"-"`
+	Role          string `json:"-" form:"-"`
+}
+
+func (r *AddArticleCoauthorRequest) Validate() error {
+	if r.ArticleSerial == "" || r.Username == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error add article co-author request: article serial and username are mandatory"))
+	}
+
+	return nil
+}
+
+type RemoveArticleCoauthorRequest struct {
+	ArticleSerial string
+	Username      string
+	ActorUsername string
+	Role          string
+}
+
+type GetArticleCoauthorsResponse struct {
+	OwnerUsername string             `json:"ownerUsername"`
+	Coauthors     []*ArticleCoauthor `json:"coauthors"`
 }
 
 type VersionTag struct {
@@ -132,6 +286,10 @@ type UpdateArticleVersionStatusRequest struct {
 	ArticleSerial string
 	VersionSerial string
 	NewStatus     string
+	Note          string // review note, e.g. the changes requested by reviewer
+	Username      string `json:"-" form:"-"`
+	Role          string `json:"-" form:"-"`
+	Reason        string `json:"-" form:"-"` // recorded in status event, default is status updated
 }
 
 func (r *UpdateArticleVersionStatusRequest) Validate() error {
@@ -144,15 +302,20 @@ func (r *UpdateArticleVersionStatusRequest) Validate() error {
 	if StringToVersionRole(r.NewStatus) == VersionStatusUnknown {
 		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error update article version status: version status is unknown"))
 	}
+	if StringToVersionRole(r.NewStatus) == VersionStatusDeleted {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error update article version status: version is deleted by deleting the article"))
+	}
 
 	return nil
 }
 
 type CreateArticleVersionRequest struct {
-	ArticleSerial string
-	Title         string
-	Content       string
-	TagSerials    []string
+	ArticleSerial     string
+	BaseVersionSerial string // version the new version is based on, used to detect conflicting edits
+	Title             string
+	Content           string
+	TagSerials        []string
+	SuggestTags       bool // return tag suggestions for the new version
 }
 
 func (r *CreateArticleVersionRequest) Validate() error {
@@ -170,20 +333,104 @@ func (r *CreateArticleVersionRequest) Validate() error {
 }
 
 type CreateArticleVersionResponse struct {
-	ArticleSerial string   `json:"articleSerial"`
-	AuthorId      string   `json:"authorId"`
-	Version       *Version `json:"version"`
+	ArticleSerial string           `json:"articleSerial"`
+	AuthorId      string           `json:"authorId"`
+	Version       *Version         `json:"version"`
+	SuggestedTags []*TagSuggestion `json:"suggestedTags,omitempty"`
+}
+
+type UpdateVersionTagsRequest struct {
+	ArticleSerial string
+	VersionSerial string
+	TagSerials    []string
+	Username      string `json:"-" form:"-"`
+	Role          string `json:"-" form:"-"`
+}
+
+func (r *UpdateVersionTagsRequest) Validate() error {
+	if r.ArticleSerial == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error update version tags request: article serial is mandatory"))
+	}
+	if r.VersionSerial == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error update version tags request: version serial is mandatory"))
+	}
+
+	return nil
+}
+
+type RestoreArticleVersionRequest struct {
+	ArticleSerial string
+	VersionSerial string
+}
+
+func (r *RestoreArticleVersionRequest) Validate() error {
+	if r.ArticleSerial == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error restore article version request: article serial is mandatory"))
+	}
+	if r.VersionSerial == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error restore article version request: version serial is mandatory"))
+	}
+
+	return nil
+}
+
+type MergeArticleVersionsRequest struct {
+	ArticleSerial       string
+	BaseVersionSerial   string
+	OursVersionSerial   string
+	TheirsVersionSerial string
+}
+
+func (r *MergeArticleVersionsRequest) Validate() error {
+	if r.ArticleSerial == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error merge article versions request: article serial is mandatory"))
+	}
+	if r.BaseVersionSerial == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error merge article versions request: base version serial is mandatory"))
+	}
+	if r.OursVersionSerial == "" || r.TheirsVersionSerial == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error merge article versions request: ours and theirs version serial are mandatory"))
+	}
+	if r.OursVersionSerial == r.TheirsVersionSerial {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error merge article versions request: ours and theirs version must be different"))
+	}
+
+	return nil
+}
+
+const (
+	MergeFieldTitle   = "title"
+	MergeFieldContent = "content"
+)
+
+type MergeConflict struct {
+	Field     string   `json:"field"`
+	StartLine int      `json:"startLine"`
+	EndLine   int      `json:"endLine"`
+	Base      []string `json:"base"`
+	Ours      []string `json:"ours"`
+	Theirs    []string `json:"theirs"`
+}
+
+type MergeArticleVersionsResponse struct {
+	ArticleSerial string           `json:"articleSerial"`
+	AuthorId      string           `json:"authorId"`
+	Version       *Version         `json:"version"`
+	HasConflicts  bool             `json:"hasConflicts"`
+	Conflicts     []*MergeConflict `json:"conflicts"`
 }
 
 type GetArticlesRequest struct {
-	Status         string `form:"status"`
-	AuthorUsername string `form:"authorUsername"`
-	TagSerial      string `form:"tagSerial"`
-	Page           int    `form:"page"`
-	PageSize       int    `form:"pageSize"`
-	Pagination     *Pagination
-	SortBy         string `form:"sortBy"`   // created_at, updated_at, published_at, tag_relationship_score
-	SortType       string `form:"sortType"` // asc, desc
+	Status             string `form:"status"`
+	AuthorUsername     string `form:"authorUsername"`
+	TagSerial          string `form:"tagSerial"`
+	IncludeDescendants bool   `form:"includeDescendants"` // also match articles tagged with any descendant of the tag
+	Query              string `form:"q"`                  // full-text search on title and content
+	Page               int    `form:"page"`
+	PageSize           int    `form:"pageSize"`
+	Pagination         *Pagination
+	SortBy             string `form:"sortBy"`   // created_at, updated_at, published_at, tag_relationship_score, relevance
+	SortType           string `form:"sortType"` // asc, desc
 }
 
 var (
@@ -192,6 +439,7 @@ var (
 		SortByUpdatedAt:            true,
 		SortByPublishedAt:          true,
 		SortByTagRelationshipScore: true,
+		SortByRelevance:            true,
 	}
 	validSortType = map[string]bool{
 		SortTypeAsc:  true,
@@ -209,6 +457,13 @@ func (r *GetArticlesRequest) Validate() error {
 	if r.SortType != "" && !validSortType[r.SortType] {
 		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error get artiles: sort type value is unknown"))
 	}
+	if r.SortBy == SortByRelevance && r.Query == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error get artiles: sort by relevance needs search query"))
+	}
+	// search results are ranked by relevance unless sorted otherwise
+	if r.Query != "" && r.SortBy == "" {
+		r.SortBy = SortByRelevance
+	}
 	return nil
 }
 
@@ -217,6 +472,37 @@ type GetArticlesResponse struct {
 	Pagination *Pagination `json:"pagination"`
 }
 
+type GetRelatedArticlesRequest struct {
+	ArticleSerial string
+	MinScore      *float32 `form:"minScore"` // default from config
+	Page          int      `form:"page"`
+	PageSize      int      `form:"pageSize"`
+	Pagination    *Pagination
+}
+
+func (r *GetRelatedArticlesRequest) Validate() error {
+	if r.ArticleSerial == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error get related articles: article serial is mandatory"))
+	}
+	if r.MinScore != nil && *r.MinScore < 0 {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error get related articles: min score can not be negative"))
+	}
+	if r.Pagination != nil {
+		r.Pagination.Validate()
+	}
+	return nil
+}
+
+type RelatedArticle struct {
+	Score   float32  `json:"score"`
+	Version *Version `json:"version"` // published version of the related article
+}
+
+type GetRelatedArticlesResponse struct {
+	Articles   []*RelatedArticle `json:"articles"`
+	Pagination *Pagination       `json:"pagination"`
+}
+
 type GetArticleLatestDetailResponse struct {
 	PublishedVersion *Version `json:"publishedVersion"`
 	LatestVersion    *Version `json:"latestVersion"`
@@ -235,3 +521,85 @@ type GetVersionsByQueryRequest struct {
 	ArticleSerial string
 	Status        string
 }
+
+type GetVersionDiffRequest struct {
+	ArticleSerial     string
+	FromVersionSerial string
+	ToVersionSerial   string
+	ContextLines      *int `form:"contextLines"`
+}
+
+func (r *GetVersionDiffRequest) Validate() error {
+	if r.ArticleSerial == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error get version diff request: article serial is mandatory"))
+	}
+	if r.FromVersionSerial == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error get version diff request: from version serial is mandatory"))
+	}
+	if r.ToVersionSerial == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error get version diff request: to version serial is mandatory"))
+	}
+	if r.ContextLines != nil && *r.ContextLines < 0 {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error get version diff request: context lines must not be negative"))
+	}
+
+	return nil
+}
+
+type TagDiff struct {
+	Added     []*Tag `json:"added"`
+	Removed   []*Tag `json:"removed"`
+	Unchanged []*Tag `json:"unchanged"`
+}
+
+type GetVersionDiffResponse struct {
+	ArticleSerial     string             `json:"articleSerial"`
+	FromVersionSerial string             `json:"fromVersionSerial"`
+	FromVersionNumber int                `json:"fromVersionNumber"`
+	ToVersionSerial   string             `json:"toVersionSerial"`
+	ToVersionNumber   int                `json:"toVersionNumber"`
+	Title             *diffutil.TextDiff `json:"title"`
+	Content           *diffutil.TextDiff `json:"content"`
+	Tags              *TagDiff           `json:"tags"`
+}
+
+type VersionReview struct {
+	VersionSerial    string    `json:"versionSerial"`
+	FromStatus       string    `json:"fromStatus"`
+	ToStatus         string    `json:"toStatus"`
+	ReviewerUsername string    `json:"reviewerUsername"`
+	Note             *string   `json:"note"`
+	CreatedAt        time.Time `json:"createdAt"`
+}
+
+type GetVersionReviewsResponse struct {
+	Reviews []*VersionReview `json:"reviews"`
+}
+
+const (
+	VersionStatusEventReasonStatusUpdated       = "status_updated"
+	VersionStatusEventReasonReplacedByPublished = "replaced_by_published"
+	VersionStatusEventReasonArticleDeleted      = "article_deleted"
+	VersionStatusEventReasonScheduled           = "scheduled"
+	VersionStatusEventReasonArticleRestored     = "article_restored"
+)
+
+type VersionStatusEvent struct {
+	ArticleSerial string    `json:"articleSerial"`
+	VersionSerial string    `json:"versionSerial"`
+	VersionNumber int       `json:"versionNumber"`
+	FromStatus    string    `json:"fromStatus"`
+	ToStatus      string    `json:"toStatus"`
+	ActorUsername *string   `json:"actorUsername"`
+	Reason        string    `json:"reason"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+type GetArticleTimelineRequest struct {
+	ArticleSerial   string
+	PublicationOnly bool `form:"publicationOnly"` // only events from or to published status
+}
+
+type GetArticleTimelineResponse struct {
+	Events []*VersionStatusEvent `json:"events"`
+}
@@ -0,0 +1,70 @@
+package entity
+
+import (
+	errorutil "article-versioning-api/utils/error"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	ScheduleActionPublish   = "publish"
+	ScheduleActionUnpublish = "unpublish"
+
+	ScheduleStatusPending   = "pending"
+	ScheduleStatusDone      = "done"
+	ScheduleStatusFailed    = "failed"
+	ScheduleStatusCancelled = "cancelled"
+)
+
+type VersionSchedule struct {
+	Serial        string     `json:"serial"`
+	ArticleSerial string     `json:"articleSerial"`
+	VersionSerial string     `json:"versionSerial"`
+	Action        string     `json:"action"`
+	ScheduledAt   time.Time  `json:"scheduledAt"`
+	Status        string     `json:"status"`
+	CreatedBy     string     `json:"createdBy"`
+	CreatedByRole string     `json:"createdByRole"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	ExecutedAt    *time.Time `json:"executedAt"`
+	Error         *string    `json:"error"`
+}
+
+type ScheduleArticleVersionRequest struct {
+	ArticleSerial string
+	VersionSerial string
+	PublishAt     *time.Time
+	UnpublishAt   *time.Time
+}
+
+func (r *ScheduleArticleVersionRequest) Validate() error {
+	if r.ArticleSerial == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error schedule article version request: article serial is mandatory"))
+	}
+	if r.VersionSerial == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error schedule article version request: version serial is mandatory"))
+	}
+	if r.PublishAt == nil && r.UnpublishAt == nil {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error schedule article version request: publish at or unpublish at is mandatory"))
+	}
+	if r.PublishAt != nil && r.PublishAt.Before(time.Now()) {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error schedule article version request: publish at must be in the future"))
+	}
+	if r.UnpublishAt != nil && r.UnpublishAt.Before(time.Now()) {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error schedule article version request: unpublish at must be in the future"))
+	}
+	if r.PublishAt != nil && r.UnpublishAt != nil && !r.UnpublishAt.After(*r.PublishAt) {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error schedule article version request: unpublish at must be after publish at"))
+	}
+
+	return nil
+}
+
+type ScheduleArticleVersionResponse struct {
+	Schedules []*VersionSchedule `json:"schedules"`
+}
+
+type GetVersionSchedulesResponse struct {
+	Schedules []*VersionSchedule `json:"schedules"`
+}
@@ -0,0 +1,213 @@
+package entity
+
+import (
+	errorutil "article-versioning-api/utils/error"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	TagGraphFormatJSON    = "json"
+	TagGraphFormatGraphML = "graphml"
+	TagGraphFormatDOT     = "dot"
+
+	defaultTagGraphLimit = 100
+	maxTagGraphLimit     = 1000
+)
+
+type GetTagGraphRequest struct {
+	Format          string `form:"format"`          // json (default), graphml or dot
+	MinCooccurrence int    `form:"minCooccurrence"` // minimum published articles with both tags for an edge, default 1
+	Limit           int    `form:"limit"`           // top N tags by usage count
+}
+
+func (r *GetTagGraphRequest) Validate() error {
+	if r.Format == "" {
+		r.Format = TagGraphFormatJSON
+	}
+	if r.Format != TagGraphFormatJSON && r.Format != TagGraphFormatGraphML && r.Format != TagGraphFormatDOT {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error get tag graph request: format '%s' is unknown, use json, graphml or dot", r.Format))
+	}
+	if r.MinCooccurrence < 0 {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error get tag graph request: min co-occurrence can not be negative"))
+	}
+	if r.MinCooccurrence == 0 {
+		r.MinCooccurrence = 1
+	}
+	if r.Limit <= 0 {
+		r.Limit = defaultTagGraphLimit
+	}
+	if r.Limit > maxTagGraphLimit {
+		r.Limit = maxTagGraphLimit
+	}
+
+	return nil
+}
+
+type TagGraphNode struct {
+	Serial        string
+	Name          string
+	ParentSerial  *string
+	UsageCount    int
+	TrendingScore float32
+}
+
+// TagGraphEdge is an undirected edge between two tags used together in published articles
+type TagGraphEdge struct {
+	Source string
+	Target string
+	Weight int     // published articles with both tags
+	PMI    float32 // positive PMI of the tags
+}
+
+type TagGraph struct {
+	Nodes []*TagGraphNode
+	Edges []*TagGraphEdge
+}
+
+// JSONGraph returns the graph in JSON Graph Format (https://jsongraphformat.info)
+func (g *TagGraph) JSONGraph() map[string]any {
+	nodes := make(map[string]any)
+	for _, n := range g.Nodes {
+		nodes[n.Serial] = map[string]any{
+			"label": n.Name,
+			"metadata": map[string]any{
+				"parentSerial":  n.ParentSerial,
+				"usageCount":    n.UsageCount,
+				"trendingScore": n.TrendingScore,
+			},
+		}
+	}
+
+	edges := []any{}
+	for _, e := range g.Edges {
+		edges = append(edges, map[string]any{
+			"source": e.Source,
+			"target": e.Target,
+			"metadata": map[string]any{
+				"weight": e.Weight,
+				"pmi":    e.PMI,
+			},
+		})
+	}
+
+	return map[string]any{
+		"graph": map[string]any{
+			"directed": false,
+			"label":    "tag co-occurrence",
+			"nodes":    nodes,
+			"edges":    edges,
+		},
+	}
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// GraphML returns the graph as GraphML document (http://graphml.graphdrawing.org)
+func (g *TagGraph) GraphML() ([]byte, error) {
+	doc := graphML{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "name", For: "node", AttrName: "name", AttrType: "string"},
+			{ID: "parentSerial", For: "node", AttrName: "parentSerial", AttrType: "string"},
+			{ID: "usageCount", For: "node", AttrName: "usageCount", AttrType: "int"},
+			{ID: "trendingScore", For: "node", AttrName: "trendingScore", AttrType: "double"},
+			{ID: "weight", For: "edge", AttrName: "weight", AttrType: "int"},
+			{ID: "pmi", For: "edge", AttrName: "pmi", AttrType: "double"},
+		},
+		Graph: graphMLGraph{ID: "tags", EdgeDefault: "undirected"},
+	}
+
+	for _, n := range g.Nodes {
+		data := []graphMLData{{Key: "name", Value: n.Name}}
+		if n.ParentSerial != nil {
+			data = append(data, graphMLData{Key: "parentSerial", Value: *n.ParentSerial})
+		}
+		data = append(data,
+			graphMLData{Key: "usageCount", Value: fmt.Sprint(n.UsageCount)},
+			graphMLData{Key: "trendingScore", Value: fmt.Sprint(n.TrendingScore)},
+		)
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{ID: n.Serial, Data: data})
+	}
+	for _, e := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			Source: e.Source,
+			Target: e.Target,
+			Data: []graphMLData{
+				{Key: "weight", Value: fmt.Sprint(e.Weight)},
+				{Key: "pmi", Value: fmt.Sprint(e.PMI)},
+			},
+		})
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error encode tag graph to graphml: %s", err.Error())
+	}
+
+	return append([]byte(xml.Header), out...), nil
+}
+
+// DOT returns the graph in Graphviz DOT language, edges are labelled with their PMI
+func (g *TagGraph) DOT() []byte {
+	var b bytes.Buffer
+
+	b.WriteString("graph tags {\n")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(&b, "  %s [label=%s, usage_count=%d, trending_score=%.4f];\n",
+			dotQuote(n.Serial), dotQuote(n.Name), n.UsageCount, n.TrendingScore)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s -- %s [weight=%d, pmi=%.4f, label=\"%.2f\"];\n",
+			dotQuote(e.Source), dotQuote(e.Target), e.Weight, e.PMI, e.PMI)
+	}
+	b.WriteString("}\n")
+
+	return b.Bytes()
+}
+
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
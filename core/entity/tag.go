@@ -2,22 +2,114 @@ package entity
 
 import (
 	errorutil "article-versioning-api/utils/error"
+	"fmt"
+	"regexp"
+	"strconv"
 	"time"
 
 	"github.com/pkg/errors"
 )
 
 type CreateTagRequest struct {
-	Name string
+	Name         string
+	ParentSerial *string // optional, tag is a root tag when empty
 }
 
 func (r *CreateTagRequest) Validate() error {
 	if r.Name == "" {
 		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error create tag request: name is mandatory"))
 	}
+	if r.ParentSerial != nil && *r.ParentSerial == "" {
+		r.ParentSerial = nil
+	}
+	return nil
+}
+
+type UpdateTagParentRequest struct {
+	TagSerial    string  `json:"-"`
+	ParentSerial *string `json:"parentSerial"` // null to move the tag to the root
+}
+
+func (r *UpdateTagParentRequest) Validate() error {
+	if r.TagSerial == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error update tag parent request: tag serial is mandatory"))
+	}
+	if r.ParentSerial != nil && *r.ParentSerial == "" {
+		r.ParentSerial = nil
+	}
+	if r.ParentSerial != nil && *r.ParentSerial == r.TagSerial {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error update tag parent request: tag can not be its own parent"))
+	}
+	return nil
+}
+
+type RenameTagRequest struct {
+	TagSerial string `json:"-"`
+	Name      string `json:"name"`
+}
+
+func (r *RenameTagRequest) Validate() error {
+	if r.TagSerial == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error rename tag request: tag serial is mandatory"))
+	}
+	if r.Name == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error rename tag request: name is mandatory"))
+	}
+	return nil
+}
+
+type MergeTagRequest struct {
+	SourceSerial string `json:"-"`
+	TargetSerial string `json:"targetSerial"`
+}
+
+func (r *MergeTagRequest) Validate() error {
+	if r.SourceSerial == "" || r.TargetSerial == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error merge tag request: source and target serial are mandatory"))
+	}
+	if r.SourceSerial == r.TargetSerial {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error merge tag request: tag can not be merged into itself"))
+	}
 	return nil
 }
 
+// TagSynonym is an alias name of a canonical tag, resolved case-insensitively
+type TagSynonym struct {
+	Name      string    `json:"name"`
+	TagSerial string    `json:"tagSerial"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type CreateTagSynonymRequest struct {
+	TagSerial string `json:"-"`
+	Name      string `json:"name"`
+}
+
+func (r *CreateTagSynonymRequest) Validate() error {
+	if r.TagSerial == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error create tag synonym request: tag serial is mandatory"))
+	}
+	if r.Name == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error create tag synonym request: name is mandatory"))
+	}
+	return nil
+}
+
+type GetTagSynonymsResponse struct {
+	Synonyms []*TagSynonym `json:"synonyms"`
+}
+
+// InvalidTagsDetail lists why the tags of a version are rejected
+type InvalidTagsDetail struct {
+	InvalidTagSerials []string `json:"invalidTagSerials,omitempty"` // not a tag serial, name nor synonym
+	MaxTags           int      `json:"maxTags,omitempty"`           // set when there are too many tags
+	TotalTags         int      `json:"totalTags,omitempty"`
+}
+
+type GetTagChildrenResponse struct {
+	Tags []*TagDetail `json:"tags"`
+}
+
 type GetTagsRequest struct {
 	Page       int `form:"page"`
 	PageSize   int `form:"pageSize"`
@@ -25,13 +117,15 @@ type GetTagsRequest struct {
 }
 
 type Tag struct {
-	Serial string `json:"serial"`
-	Name   string `json:"name"`
+	Serial       string  `json:"serial"`
+	Name         string  `json:"name"`
+	ParentSerial *string `json:"parentSerial,omitempty"`
 }
 
 type TagDetail struct {
 	Serial        string  `json:"serial"`
 	Name          string  `json:"name"`
+	ParentSerial  *string `json:"parentSerial"`
 	UsageCount    int     `json:"usageCount"`
 	TrendingScore float32 `json:"trendingScore"`
 }
@@ -52,3 +146,148 @@ type TagPairStat struct {
 
 type GetTagStatsRequest struct {
 }
+
+const (
+	defaultTagTrendDays = 30
+	maxTrendingWindow   = 365 * 24 * time.Hour
+)
+
+// window is a number of hours or days, e.g. 24h or 7d
+var trendingWindowRegex = regexp.MustCompile(`^([1-9][0-9]*)(h|d)$`)
+
+type GetTrendingTagsRequest struct {
+	Window         string `form:"window"` // optional, e.g. 24h, 7d
+	WindowDuration time.Duration
+	Page           int `form:"page"`
+	PageSize       int `form:"pageSize"`
+	Pagination     *Pagination
+}
+
+func (r *GetTrendingTagsRequest) Validate() error {
+	if r.Pagination != nil {
+		r.Pagination.Validate()
+	}
+	if r.Window == "" {
+		return nil
+	}
+
+	match := trendingWindowRegex.FindStringSubmatch(r.Window)
+	if match == nil {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error get trending tags: window '%s' is invalid, use hours or days e.g. 24h or 7d", r.Window))
+	}
+	value, err := strconv.Atoi(match[1])
+	if err != nil {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error get trending tags: window '%s' is invalid", r.Window))
+	}
+
+	unit := time.Hour
+	if match[2] == "d" {
+		unit = 24 * time.Hour
+	}
+	r.WindowDuration = time.Duration(value) * unit
+	if r.WindowDuration > maxTrendingWindow {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error get trending tags: window can not be longer than 365 days"))
+	}
+
+	return nil
+}
+
+type TrendingTag struct {
+	Serial        string   `json:"serial"`
+	Name          string   `json:"name"`
+	UsageCount    int      `json:"usageCount"`
+	TrendingScore float32  `json:"trendingScore"`         // average score within the window when window is set
+	ScoreChange   *float32 `json:"scoreChange,omitempty"` // latest minus earliest score within the window
+}
+
+type GetTrendingTagsResponse struct {
+	Tags       []*TrendingTag `json:"tags"`
+	Pagination *Pagination    `json:"pagination"`
+}
+
+type GetTagTrendRequest struct {
+	TagSerial string
+	From      *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"` // default 30 days ago
+	To        *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`   // default now
+}
+
+func (r *GetTagTrendRequest) Validate() error {
+	if r.TagSerial == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error get tag trend: tag serial is mandatory"))
+	}
+
+	if r.To == nil {
+		to := time.Now()
+		r.To = &to
+	}
+	if r.From == nil {
+		from := r.To.AddDate(0, 0, -defaultTagTrendDays)
+		r.From = &from
+	}
+	if !r.From.Before(*r.To) {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error get tag trend: from must be before to"))
+	}
+
+	utcFrom, utcTo := r.From.UTC(), r.To.UTC()
+	r.From, r.To = &utcFrom, &utcTo
+
+	return nil
+}
+
+// TagTrendingSnapshot is the tag stat recorded on every trending score update by worker
+type TagTrendingSnapshot struct {
+	UsageCount    int       `json:"usageCount"`
+	TrendingScore float32   `json:"trendingScore"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+type GetTagTrendResponse struct {
+	Tag       *TagDetail             `json:"tag"`
+	From      time.Time              `json:"from"`
+	To        time.Time              `json:"to"`
+	Snapshots []*TagTrendingSnapshot `json:"snapshots"`
+}
+
+const (
+	defaultTagSuggestionLimit = 5
+	maxTagSuggestionLimit     = 20
+)
+
+type SuggestTagsRequest struct {
+	Title      string   `json:"title"`
+	Content    string   `json:"content"`
+	TagSerials []string `json:"tagSerials"` // tags already picked, used for co-occurrence and excluded from suggestions
+	Limit      int      `json:"limit"`
+}
+
+func (r *SuggestTagsRequest) Validate() error {
+	if r.Title == "" && r.Content == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error suggest tags request: title or content is mandatory"))
+	}
+	if r.Limit <= 0 {
+		r.Limit = defaultTagSuggestionLimit
+	}
+	if r.Limit > maxTagSuggestionLimit {
+		r.Limit = maxTagSuggestionLimit
+	}
+	return nil
+}
+
+type TagSuggestion struct {
+	Tag               *Tag    `json:"tag"`
+	Score             float32 `json:"score"`
+	ContentScore      float32 `json:"contentScore"`      // TF-IDF match of the text with the published versions of the tag
+	CooccurrenceScore float32 `json:"cooccurrenceScore"` // how often the tag is used together with the picked tags
+}
+
+type SuggestTagsResponse struct {
+	Suggestions []*TagSuggestion `json:"suggestions"`
+}
+
+// TagLexemeStats is the usage of lexemes in published versions, overall and per tag
+type TagLexemeStats struct {
+	TotalVersion          int
+	LexemeVersionCount    map[string]int            // lexeme -> published versions containing it
+	TagVersionCount       map[string]int            // tag serial -> published versions with the tag
+	TagLexemeVersionCount map[string]map[string]int // tag serial -> lexeme -> published versions with the tag containing it
+}
@@ -2,14 +2,18 @@ package entity
 
 import (
 	errorutil "article-versioning-api/utils/error"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 const (
-	ContextUsername = "username"
-	ContextRole     = "role"
+	ContextUsername       = "username"
+	ContextRole           = "role"
+	ContextTokenID        = "tokenId"
+	ContextTokenExpiresAt = "tokenExpiresAt"
 )
 
 func GetContextUsername(ctx *gin.Context) string {
@@ -22,11 +26,24 @@ func GetContextRole(ctx *gin.Context) string {
 	return role.(string)
 }
 
+func GetContextTokenID(ctx *gin.Context) string {
+	tokenID, _ := ctx.Get(ContextTokenID)
+	id, _ := tokenID.(string)
+	return id
+}
+
+func GetContextTokenExpiresAt(ctx *gin.Context) time.Time {
+	expiresAt, _ := ctx.Get(ContextTokenExpiresAt)
+	t, _ := expiresAt.(time.Time)
+	return t
+}
+
 type RegisterUserRequest struct {
-	Username string
-	Password string
-	Hash     string
-	Role     string
+	Username        string
+	Password        string
+	Hash            string
+	Role            string // optional, reader without invitation, must match the invitation role otherwise
+	InvitationToken string // mandatory for roles other than reader
 }
 
 func (r *RegisterUserRequest) Validate() error {
@@ -34,15 +51,48 @@ func (r *RegisterUserRequest) Validate() error {
 		return errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error register user request: username is mandatory"))
 	}
 	if r.Password == "" {
-		return errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error register user request: username is mandatory"))
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error register user request: password is mandatory"))
 	}
-	if StringToUserRole(r.Role) == UserRoleUnknown {
+	if r.Role != "" && StringToUserRole(r.Role) == UserRoleUnknown {
 		return errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error register user request: user role is not valid"))
 	}
 
 	return nil
 }
 
+type CreateInvitationRequest struct {
+	Role          string
+	Username      string // optional, only this username can use the invitation
+	ActorUsername string `json:"-" form:"-"`
+}
+
+func (r *CreateInvitationRequest) Validate() error {
+	if StringToUserRole(r.Role) == UserRoleUnknown {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error create invitation request: user role is not valid"))
+	}
+
+	return nil
+}
+
+type CreateInvitationResponse struct {
+	Token     string    `json:"token"` // only returned once, the token is stored hashed
+	Role      string    `json:"role"`
+	Username  *string   `json:"username"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Invitation allows to register once with its role, it is stored hashed
+type Invitation struct {
+	TokenHash         string
+	Role              string
+	Username          *string // only this username can use the invitation when set
+	CreatedByUsername string
+	ExpiresAt         time.Time
+	CreatedAt         time.Time
+	UsedAt            *time.Time
+	UsedByUsername    *string
+}
+
 type UserRole int
 
 const (
@@ -82,12 +132,202 @@ func StringToUserRole(ur string) UserRole {
 }
 
 type User struct {
-	Username string
-	Role     string
-	Hash     string
+	Username              string
+	Role                  string
+	Hash                  string
+	IsActive              bool
+	PasswordResetRequired bool
+}
+
+// UserDetail is a user without its password hash
+type UserDetail struct {
+	Username              string     `json:"username"`
+	Role                  string     `json:"role"`
+	IsActive              bool       `json:"isActive"`
+	PasswordResetRequired bool       `json:"passwordResetRequired"`
+	CreatedAt             time.Time  `json:"createdAt"`
+	UpdatedAt             *time.Time `json:"updatedAt"`
+}
+
+type GetUsersRequest struct {
+	Query      string `form:"q"` // search in username
+	Role       string `form:"role"`
+	IsActive   *bool  `form:"isActive"`
+	Page       int    `form:"page"`
+	PageSize   int    `form:"pageSize"`
+	Pagination *Pagination
+}
+
+func (r *GetUsersRequest) Validate() error {
+	if r.Pagination != nil {
+		r.Pagination.Validate()
+	}
+	if r.Role != "" && StringToUserRole(r.Role) == UserRoleUnknown {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error get users request: user role is not valid"))
+	}
+
+	return nil
+}
+
+type GetUsersResponse struct {
+	Users      []*UserDetail `json:"users"`
+	Pagination *Pagination   `json:"pagination"`
+}
+
+type UpdateUserRoleRequest struct {
+	Username      string `json:"-" form:"-"`
+	Role          string
+	ActorUsername string `json:"-" form:"-"`
+}
+
+func (r *UpdateUserRoleRequest) Validate() error {
+	if r.Username == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error update user role request: username is mandatory"))
+	}
+	if StringToUserRole(r.Role) == UserRoleUnknown {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error update user role request: user role is not valid"))
+	}
+	// an admin can not lock themself out
+	if r.Username == r.ActorUsername {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error update user role request: can not change own role"))
+	}
+
+	return nil
+}
+
+type UpdateUserStatusRequest struct {
+	Username      string `json:"-" form:"-"`
+	IsActive      *bool
+	ActorUsername string `json:"-" form:"-"`
+}
+
+func (r *UpdateUserStatusRequest) Validate() error {
+	if r.Username == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error update user status request: username is mandatory"))
+	}
+	if r.IsActive == nil {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error update user status request: isActive is mandatory"))
+	}
+	if r.Username == r.ActorUsername {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error update user status request: can not change own status"))
+	}
+
+	return nil
+}
+
+type ForcePasswordResetRequest struct {
+	Username      string
+	ActorUsername string
+}
+
+type ForcePasswordResetResponse struct {
+	TemporaryPassword string `json:"temporaryPassword"` // only valid to set a new password
+}
+
+type ResetPasswordRequest struct {
+	Username    string
+	Password    string // current or temporary password
+	NewPassword string
+}
+
+func (r *ResetPasswordRequest) Validate() error {
+	if r.Username == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error reset password request: username is mandatory"))
+	}
+	if r.Password == "" || r.NewPassword == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error reset password request: password and new password are mandatory"))
+	}
+	if r.Password == r.NewPassword {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error reset password request: new password must be different"))
+	}
+
+	return nil
+}
+
+const (
+	UserAuditActionRoleChanged         = "role_changed"
+	UserAuditActionDeactivated         = "deactivated"
+	UserAuditActionReactivated         = "reactivated"
+	UserAuditActionPasswordResetForced = "password_reset_forced"
+	UserAuditActionPasswordChanged     = "password_changed"
+	UserAuditActionInvitationAccepted  = "invitation_accepted"
+	UserAuditActionAdminBootstrapped   = "admin_bootstrapped"
+)
+
+type UserAuditLog struct {
+	TargetUsername string    `json:"targetUsername"`
+	ActorUsername  string    `json:"actorUsername"`
+	Action         string    `json:"action"`
+	OldValue       *string   `json:"oldValue"`
+	NewValue       *string   `json:"newValue"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+type GetUserAuditLogsRequest struct {
+	Username   string
+	Page       int `form:"page"`
+	PageSize   int `form:"pageSize"`
+	Pagination *Pagination
+}
+
+type GetUserAuditLogsResponse struct {
+	Logs       []*UserAuditLog `json:"logs"`
+	Pagination *Pagination     `json:"pagination"`
 }
 
 type LoginRequest struct {
 	Username string
 	Password string
 }
+
+type LoginResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int    `json:"expiresIn"` // seconds until the token expires
+}
+
+// AccessToken is a signed JWT with its id (jti) and expiry
+type AccessToken struct {
+	Token     string
+	ID        string
+	ExpiresAt time.Time
+}
+
+// TokenClaims are the claims of a verified access token
+type TokenClaims struct {
+	Username  string
+	Role      string
+	ID        string
+	ExpiresAt time.Time
+}
+
+// RefreshToken is stored hashed, tokens rotated from the same login share the family id
+type RefreshToken struct {
+	TokenHash     string
+	FamilyID      string
+	Username      string
+	AccessTokenID string // id of the access token issued together with the refresh token
+	ExpiresAt     time.Time
+	CreatedAt     time.Time
+	UsedAt        *time.Time // set when the token is rotated, using it again is a reuse
+	RevokedAt     *time.Time
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string
+}
+
+func (r *RefreshTokenRequest) Validate() error {
+	if r.RefreshToken == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error refresh token request: refresh token is mandatory"))
+	}
+
+	return nil
+}
+
+type LogoutRequest struct {
+	RefreshToken   string    // optional, the refresh token family is revoked too
+	Username       string    `json:"-" form:"-"`
+	TokenID        string    `json:"-" form:"-"`
+	TokenExpiresAt time.Time `json:"-" form:"-"`
+}
@@ -13,14 +13,45 @@ type ArticleRepositoryInterface interface {
 	InsertArticleTx(tx *sql.Tx, article *entity.Article) error
 	InsertVersionTx(tx *sql.Tx, version *entity.Version) error
 	InsertVersionTagsTx(tx *sql.Tx, versionSerial string, tagSerials []string) error
+	ReplaceVersionTags(tx *gorm.DB, versionSerial string, tagSerials []string) error
+	GetVersionStatusForUpdate(tx *gorm.DB, serial string) (string, error)
+	GetVersionTagSerials(tx *gorm.DB, versionSerial string) ([]string, error)
 	UpdateArticleVersionStatus(tx *gorm.DB, req *entity.UpdateArticleVersionStatusRequest) error
+	InsertVersionReview(tx *gorm.DB, review *entity.VersionReview) error
+	InsertVersionStatusEvents(tx *gorm.DB, events []*entity.VersionStatusEvent) error
+	GetVersionStatusEvents(req *entity.GetArticleTimelineRequest) ([]*entity.VersionStatusEvent, error)
+	GetVersionReviews(versionSerial string) ([]*entity.VersionReview, error)
 	DeleteArticle(tx *gorm.DB, serial string) error
 	DeleteVersionByArticleSerial(tx *gorm.DB, articleSerial string) error
+	GetArticleBySerial(serial string) (*entity.Article, error)
+	GetArticleCoauthors(articleSerial string) ([]*entity.ArticleCoauthor, error)
+	IsArticleCoauthor(articleSerial, username string) (bool, error)
+	InsertArticleCoauthor(tx *gorm.DB, articleSerial string, coauthor *entity.ArticleCoauthor) error
+	DeleteArticleCoauthor(tx *gorm.DB, articleSerial, username string) error
+	RestoreArticle(tx *gorm.DB, serial string) error
+	RestoreVersion(tx *gorm.DB, req *entity.UpdateArticleVersionStatusRequest) error
+	GetVersionStatusesBeforeDeleted(articleSerial string) (map[string]string, error)
+	GetDeletedArticleSerials(retentionDays int, limit int) ([]string, error)
+	PurgeArticles(tx *gorm.DB, serials []string) error
 	GetLatestVersionNumber(articleSerial string) (int, error)
 	GetArticles(req *entity.GetArticlesRequest) (*entity.GetArticlesResponse, error)
 	GetArticleLatestDetail(articleSerial string) ([]*entity.Version, error)
 	GetVersionsByQuery(req *entity.GetVersionsByQueryRequest) ([]*entity.Version, error)
+	GetVersionsBySerials(serials []string) ([]*entity.Version, error)
+	GetTextLexemes(text string) (map[string]int, error)
+	GetTagLexemeStats(lexemes []string) (*entity.TagLexemeStats, error)
+	RebuildLexemeStats(tx *gorm.DB) error
+	GetRelatedPublishedVersionTags(tagSerials []string, excludeArticleSerial string) (map[string][]string, error)
 	GetVersionBySerial(serial string) (*entity.Version, error)
 	UpdateTagRelationshipScore(tx *gorm.DB, versionSerial string, tagRelationshipScore float32) error
 	GetTotalPublishedArticle(tx *gorm.DB) (int, error)
+	GetPublishedVersionTagSerials(afterSerial string, limit int) ([]string, map[string][]string, error)
+	UpdateTagRelationshipScores(tx *gorm.DB, mapVersionScore map[string]float32) error
+
+	InsertVersionSchedules(tx *gorm.DB, schedules []*entity.VersionSchedule) error
+	GetVersionSchedulesByArticleSerial(articleSerial string) ([]*entity.VersionSchedule, error)
+	GetDueVersionSchedules(limit int) ([]*entity.VersionSchedule, error)
+	UpdateVersionScheduleStatus(tx *gorm.DB, serial, status string, errorMessage *string) error
+	CancelVersionSchedule(tx *gorm.DB, articleSerial, serial string) error
+	CancelVersionSchedulesByArticleSerial(tx *gorm.DB, articleSerial string) error
 }
@@ -10,6 +10,26 @@ type TagRepositoryInterface interface {
 	InsertTag(tag *entity.Tag, tx *gorm.DB) error
 	GetTags(pg *entity.Pagination) ([]*entity.TagDetail, error)
 	GetTagBySerial(serial string) (*entity.TagDetail, error)
+	GetTagChildren(serial string) ([]*entity.TagDetail, error)
+	GetTagDescendantSerials(tx *gorm.DB, serial string) ([]string, error)
+	LockTagHierarchy(tx *gorm.DB) error
+	UpdateTagParent(tx *gorm.DB, serial string, parentSerial *string) error
+	UpdateTagName(tx *gorm.DB, serial, name string) error
+	MoveTagChildren(tx *gorm.DB, parentSerial string, newParentSerial *string, excludeSerial string) error
+	DeleteTag(tx *gorm.DB, serial string) error
+
+	GetTagSerialsByValues(values []string) (map[string]string, error)
+	InsertTagSynonym(tx *gorm.DB, synonym *entity.TagSynonym) error
+	GetTagSynonyms(tagSerial string) ([]*entity.TagSynonym, error)
+	DeleteTagSynonym(tagSerial, name string) error
+	MoveTagSynonyms(tx *gorm.DB, sourceSerial, targetSerial string) error
+
+	GetPublishedVersionTagSerials(tx *gorm.DB, tagSerial string) (map[string][]string, error)
+	MergeVersionTags(tx *gorm.DB, sourceSerial, targetSerial string) error
+	DeleteVersionTags(tx *gorm.DB, tagSerial string) error
+	RebuildTagStats(tx *gorm.DB) error
+	GetTagGraphNodes(limit int) ([]*entity.TagGraphNode, error)
+	RecountTagStats(tx *gorm.DB, tagSerial string) error
 
 	InsertTagStat(tagSerial string, tx *gorm.DB) error
 	DecrementUsageCount(tx *gorm.DB, tagSerials []string) error
@@ -19,5 +39,12 @@ type TagRepositoryInterface interface {
 	IncrementTagPairStat(tx *gorm.DB, tag1Serial, tag2Serial string) error
 	DecrementTagPairStat(tx *gorm.DB, tag1Serial, tag2Serial string) error
 	GetTagPairStatsBySerials(tx *gorm.DB, serials []string) ([]*entity.TagPairStat, error)
+	GetTagPairStatsByAnySerials(serials []string) ([]*entity.TagPairStat, error)
+	GetTagsBySerials(serials []string) ([]*entity.Tag, error)
 	GetTagStats(tx *gorm.DB, pg *entity.Pagination) ([]*entity.TagStat, error)
+
+	InsertTagTrendingSnapshots(tx *gorm.DB) error
+	DeleteExpiredTagTrendingSnapshots(tx *gorm.DB, retentionDays int) error
+	GetTrendingTags(req *entity.GetTrendingTagsRequest) ([]*entity.TrendingTag, error)
+	GetTagTrendingSnapshots(req *entity.GetTagTrendRequest) ([]*entity.TagTrendingSnapshot, error)
 }
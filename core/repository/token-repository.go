@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"article-versioning-api/core/entity"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type TokenRepositoryInterface interface {
+	InsertRefreshToken(tx *gorm.DB, token *entity.RefreshToken) error
+	GetRefreshTokenByHashForUpdate(tx *gorm.DB, tokenHash string) (*entity.RefreshToken, error)
+	UpdateRefreshTokenUsed(tx *gorm.DB, tokenHash string) error
+	RevokeRefreshTokenFamily(tx *gorm.DB, familyID string, accessTokenExpiresAt time.Time) error
+	RevokeUserTokens(tx *gorm.DB, username string, accessTokenExpiresAt time.Time) error
+	InsertRevokedToken(tx *gorm.DB, tokenID string, expiresAt time.Time) error
+	IsTokenRevoked(tokenID string) (bool, error)
+	DeleteExpiredTokens(tx *gorm.DB) error
+}
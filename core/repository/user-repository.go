@@ -1,8 +1,23 @@
 package repository
 
-import "article-versioning-api/core/entity"
+import (
+	"article-versioning-api/core/entity"
+
+	"gorm.io/gorm"
+)
 
 type UserRepositoryInterface interface {
-	CreateUser(req *entity.RegisterUserRequest) error
+	CreateUser(tx *gorm.DB, req *entity.RegisterUserRequest) error
 	GetUserByUsername(username string) (*entity.User, error)
+	GetUserByUsernameForUpdate(tx *gorm.DB, username string) (*entity.User, error)
+	GetUsers(req *entity.GetUsersRequest) (*entity.GetUsersResponse, error)
+	UpdateUserRole(tx *gorm.DB, username, role string) error
+	UpdateUserActive(tx *gorm.DB, username string, isActive bool) error
+	UpdateUserPassword(tx *gorm.DB, username, hash string, resetRequired bool) error
+	InsertUserAuditLog(tx *gorm.DB, log *entity.UserAuditLog) error
+	GetUserAuditLogs(req *entity.GetUserAuditLogsRequest) (*entity.GetUserAuditLogsResponse, error)
+	CountUsersByRole(tx *gorm.DB, role string) (int64, error)
+	InsertInvitation(tx *gorm.DB, invitation *entity.Invitation) error
+	GetInvitationByHashForUpdate(tx *gorm.DB, tokenHash string) (*entity.Invitation, error)
+	UpdateInvitationUsed(tx *gorm.DB, tokenHash, username string) error
 }
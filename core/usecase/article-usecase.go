@@ -4,17 +4,19 @@ import (
 	"article-versioning-api/config"
 	"article-versioning-api/core/entity"
 	"article-versioning-api/core/repository"
+	diffutil "article-versioning-api/utils/diff"
 	errorutil "article-versioning-api/utils/error"
 	generalutil "article-versioning-api/utils/general"
 	serialutil "article-versioning-api/utils/serial"
 	transactionutil "article-versioning-api/utils/transaction"
 	"errors"
 	"fmt"
-	"math"
+	"log"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
 type articleUsecase struct {
@@ -22,27 +24,52 @@ type articleUsecase struct {
 	tagRepo        repository.TagRepositoryInterface
 	transactionPkg transactionutil.Transaction
 	cfg            *config.Config
+	scorer         tagScorer
 }
 
 type ArticleUsecaseInterface interface {
 	CreateArticle(ctx *gin.Context, req *entity.CreateArticleRequest) (*entity.CreateArticleResponse, error)
-	UpdateArticleVersionStatus(req *entity.UpdateArticleVersionStatusRequest) error
-	DeleteArticle(articleSerial string) error
+	UpdateArticleVersionStatus(ctx *gin.Context, req *entity.UpdateArticleVersionStatusRequest) error
+	GetVersionReviews(articleSerial, versionSerial string) (*entity.GetVersionReviewsResponse, error)
+	DeleteArticle(ctx *gin.Context, articleSerial string) error
+	GetArticleTimeline(req *entity.GetArticleTimelineRequest) (*entity.GetArticleTimelineResponse, error)
+	RestoreDeletedArticle(ctx *gin.Context, articleSerial string) error
+	PurgeDeletedArticles() error
 	CreateArticleVersion(ctx *gin.Context, req *entity.CreateArticleVersionRequest) (resp *entity.CreateArticleVersionResponse, err error)
+	UpdateVersionTags(ctx *gin.Context, req *entity.UpdateVersionTagsRequest) (*entity.Version, error)
+	RestoreArticleVersion(ctx *gin.Context, req *entity.RestoreArticleVersionRequest) (*entity.CreateArticleVersionResponse, error)
+	MergeArticleVersions(ctx *gin.Context, req *entity.MergeArticleVersionsRequest) (*entity.MergeArticleVersionsResponse, error)
 	GetArticles(ctx *gin.Context, req *entity.GetArticlesRequest) (*entity.GetArticlesResponse, error)
 	GetArticleLatestDetail(articleSerial string) (*entity.GetArticleLatestDetailResponse, error)
 	GetVersionsByArticleSerial(articleSerial string) (*entity.GetVersionsByArticleSerialResponse, error)
+	GetRelatedArticles(req *entity.GetRelatedArticlesRequest) (*entity.GetRelatedArticlesResponse, error)
+	SuggestTags(req *entity.SuggestTagsRequest) (*entity.SuggestTagsResponse, error)
 	GetVersionBySerial(serial string) (*entity.Version, error)
+	GetVersionDiff(req *entity.GetVersionDiffRequest) (*entity.GetVersionDiffResponse, error)
 	UpdateTrendingScoreTags(pg *entity.Pagination) (err error)
+	UpdateTagRelationshipScores() error
+	UpdateLexemeStats() error
+	ScheduleArticleVersion(ctx *gin.Context, req *entity.ScheduleArticleVersionRequest) (resp *entity.ScheduleArticleVersionResponse, err error)
+	GetVersionSchedules(articleSerial string) (*entity.GetVersionSchedulesResponse, error)
+	CancelVersionSchedule(ctx *gin.Context, articleSerial, scheduleSerial string) error
+	GetArticleCoauthors(articleSerial string) (*entity.GetArticleCoauthorsResponse, error)
+	AddArticleCoauthor(req *entity.AddArticleCoauthorRequest) error
+	RemoveArticleCoauthor(req *entity.RemoveArticleCoauthorRequest) error
+	ApplyDueVersionSchedules() error
 }
 
 func NewArticleUsecase(articleRepo repository.ArticleRepositoryInterface, tagRepo repository.TagRepositoryInterface, transactionPkg transactionutil.Transaction, cfg *config.Config) ArticleUsecaseInterface {
-	return &articleUsecase{articleRepo, tagRepo, transactionPkg, cfg}
+	return &articleUsecase{articleRepo, tagRepo, transactionPkg, cfg, tagScorer{articleRepo, tagRepo, cfg}}
 }
 
 const (
-	articleSerialPrefix = "ART"
-	versionSerialPrefix = "VER"
+	articleSerialPrefix  = "ART"
+	versionSerialPrefix  = "VER"
+	scheduleSerialPrefix = "SCH"
+
+	dueVersionScheduleBatchSize   = 100
+	purgeArticleBatchSize         = 100
+	tagRelationshipScoreBatchSize = 500
 )
 
 func (u *articleUsecase) CreateArticle(ctx *gin.Context, req *entity.CreateArticleRequest) (resp *entity.CreateArticleResponse, err error) {
@@ -54,6 +81,11 @@ func (u *articleUsecase) CreateArticle(ctx *gin.Context, req *entity.CreateArtic
 		return nil, errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error create article: user id not found in context"))
 	}
 
+	req.TagSerials, err = u.validateVersionTagSerials(req.TagSerials)
+	if err != nil {
+		return nil, err
+	}
+
 	tx, err := u.articleRepo.GetDb().Begin()
 	if err != nil {
 		return nil, fmt.Errorf("error create article: failed to begin transaction: %s", err.Error())
@@ -69,7 +101,8 @@ func (u *articleUsecase) CreateArticle(ctx *gin.Context, req *entity.CreateArtic
 	}
 
 	err = u.articleRepo.InsertArticleTx(tx, &entity.Article{
-		Serial: articleSerial,
+		Serial:        articleSerial,
+		OwnerUsername: authorUsername,
 	})
 	if err != nil {
 		return
@@ -109,7 +142,23 @@ func (u *articleUsecase) CreateArticle(ctx *gin.Context, req *entity.CreateArtic
 	return
 }
 
-func (u *articleUsecase) UpdateArticleVersionStatus(req *entity.UpdateArticleVersionStatusRequest) (err error) {
+func (u *articleUsecase) UpdateArticleVersionStatus(ctx *gin.Context, req *entity.UpdateArticleVersionStatusRequest) error {
+	req.Username = entity.GetContextUsername(ctx)
+	req.Role = entity.GetContextRole(ctx)
+	if req.Username == "" || req.Role == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error update article version status: user not found in context"))
+	}
+
+	err := u.validateArticleAccess(req.ArticleSerial, req.Username, req.Role, false)
+	if err != nil {
+		return err
+	}
+
+	return u.updateArticleVersionStatus(req)
+}
+
+// update version status following the allowed transitions, request without role is done by the system
+func (u *articleUsecase) updateArticleVersionStatus(req *entity.UpdateArticleVersionStatusRequest) (err error) {
 	err = req.Validate()
 	if err != nil {
 		return err
@@ -132,11 +181,11 @@ func (u *articleUsecase) UpdateArticleVersionStatus(req *entity.UpdateArticleVer
 	// init transaction for updating status and tag's usage count
 	tx := u.transactionPkg.InitTransaction()
 	defer func() {
-		u.transactionPkg.SettleTransaction(tx, err)
+		err = u.transactionPkg.SettleTransaction(tx, err)
 	}()
 
 	// calculate tag usage count
-	version, err := u.articleRepo.GetVersionBySerial(req.VersionSerial)
+	version, err := u.getArticleVersion(req.ArticleSerial, req.VersionSerial)
 	if err != nil {
 		return err
 	}
@@ -150,9 +199,43 @@ func (u *articleUsecase) UpdateArticleVersionStatus(req *entity.UpdateArticleVer
 	if currStatus == newStatus {
 		return nil
 	}
-	if entity.IsPublishedStatus(currStatus) == entity.IsPublishedStatus(newStatus) || !entity.IsPublishedStatus(currStatus) == !entity.IsPublishedStatus(newStatus) {
-		// published to published or non published to non published, then do nothing
-		return nil
+
+	err = entity.ValidateVersionStatusTransition(currStatus, newStatus, req.Role)
+	if err != nil {
+		return err
+	}
+
+	reason := req.Reason
+	if reason == "" {
+		reason = entity.VersionStatusEventReasonStatusUpdated
+	}
+	err = u.articleRepo.InsertVersionStatusEvents(tx, []*entity.VersionStatusEvent{
+		newVersionStatusEvent(version, newStatus, req.Username, reason),
+	})
+	if err != nil {
+		return err
+	}
+
+	if entity.IsReviewStatus(newStatus) {
+		review := &entity.VersionReview{
+			VersionSerial:    version.Serial,
+			FromStatus:       currStatus,
+			ToStatus:         newStatus,
+			ReviewerUsername: req.Username,
+		}
+		if req.Note != "" {
+			review.Note = &req.Note
+		}
+
+		err = u.articleRepo.InsertVersionReview(tx, review)
+		if err != nil {
+			return err
+		}
+	}
+
+	if entity.IsPublishedStatus(currStatus) == entity.IsPublishedStatus(newStatus) {
+		// non published to non published, tag statistics are not affected
+		return u.articleRepo.UpdateArticleVersionStatus(tx, req)
 	}
 
 	allAffectedTagSerials = append([]string{}, tagsSerials...)
@@ -169,6 +252,13 @@ func (u *articleUsecase) UpdateArticleVersionStatus(req *entity.UpdateArticleVer
 				return err
 			}
 
+			err = u.articleRepo.InsertVersionStatusEvents(tx, []*entity.VersionStatusEvent{
+				newVersionStatusEvent(currPublishedVersion, entity.VersionStatusDraft.String(), req.Username, entity.VersionStatusEventReasonReplacedByPublished),
+			})
+			if err != nil {
+				return err
+			}
+
 			// decrement tag usage count the previous pubslihed version
 			currPublishedVersionTagSerials := currPublishedVersion.TagSerials()
 			allAffectedTagSerials = append(allAffectedTagSerials, currPublishedVersionTagSerials...)
@@ -177,6 +267,10 @@ func (u *articleUsecase) UpdateArticleVersionStatus(req *entity.UpdateArticleVer
 			if err != nil {
 				return err
 			}
+			err = u.scorer.decrementTagPairStats(tx, currPublishedVersionTagSerials)
+			if err != nil {
+				return err
+			}
 		}
 
 		// increment tag usage count for new published version
@@ -184,12 +278,20 @@ func (u *articleUsecase) UpdateArticleVersionStatus(req *entity.UpdateArticleVer
 		if err != nil {
 			return err
 		}
+		err = u.scorer.incrementTagPairStats(tx, tagsSerials)
+		if err != nil {
+			return err
+		}
 	} else if entity.IsPublishedStatus(currStatus) && !entity.IsPublishedStatus(newStatus) { // unpublish
 		// decrement tag usage count this version
 		err = u.tagRepo.DecrementUsageCount(tx, tagsSerials)
 		if err != nil {
 			return err
 		}
+		err = u.scorer.decrementTagPairStats(tx, tagsSerials)
+		if err != nil {
+			return err
+		}
 	}
 
 	allAffectedTagSerials = generalutil.SanitizeDuplicateSerials(allAffectedTagSerials)
@@ -198,7 +300,7 @@ func (u *articleUsecase) UpdateArticleVersionStatus(req *entity.UpdateArticleVer
 		return err
 	}
 
-	err = u.updateTrendingScore(tx, allTagStats)
+	err = u.scorer.updateTrendingScore(tx, allTagStats)
 	if err != nil {
 		return err
 	}
@@ -209,19 +311,8 @@ func (u *articleUsecase) UpdateArticleVersionStatus(req *entity.UpdateArticleVer
 		return err
 	}
 
-	// update tag relationship score
-	// generate pair and record the increment
-	tagSerialPairCombination := generatePairCombination(tagsSerials)
-	for _, pair := range tagSerialPairCombination {
-		if len(pair) >= 2 {
-			err = u.tagRepo.IncrementTagPairStat(tx, pair[0], pair[1])
-			if err != nil {
-				return err
-			}
-		}
-	}
 	// calculate tag relationship score based on tag usage count and its pair that increase and or decrease before
-	err = u.updateTagRelationshipScore(tx, version.Serial, tagsSerials)
+	err = u.scorer.updateTagRelationshipScore(tx, version.Serial, tagsSerials)
 	if err != nil {
 		return err
 	}
@@ -229,6 +320,22 @@ func (u *articleUsecase) UpdateArticleVersionStatus(req *entity.UpdateArticleVer
 	return err
 }
 
+func (u *articleUsecase) GetVersionReviews(articleSerial, versionSerial string) (*entity.GetVersionReviewsResponse, error) {
+	version, err := u.getArticleVersion(articleSerial, versionSerial)
+	if err != nil {
+		return nil, err
+	}
+
+	reviews, err := u.articleRepo.GetVersionReviews(version.Serial)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.GetVersionReviewsResponse{
+		Reviews: reviews,
+	}, nil
+}
+
 func (u *articleUsecase) GetArticles(ctx *gin.Context, req *entity.GetArticlesRequest) (*entity.GetArticlesResponse, error) {
 	if err := req.Validate(); err != nil {
 		return nil, err
@@ -239,6 +346,17 @@ func (u *articleUsecase) GetArticles(ctx *gin.Context, req *entity.GetArticlesRe
 		req.Status = entity.VersionStatusPublished.String()
 	}
 
+	// tag can be filtered by its name or synonym too
+	if req.TagSerial != "" {
+		mapValueSerial, err := u.tagRepo.GetTagSerialsByValues([]string{req.TagSerial})
+		if err != nil {
+			return nil, err
+		}
+		if serial, ok := mapValueSerial[req.TagSerial]; ok {
+			req.TagSerial = serial
+		}
+	}
+
 	resp, err := u.articleRepo.GetArticles(req)
 	if err != nil {
 		return nil, err
@@ -286,11 +404,31 @@ func (u *articleUsecase) CreateArticleVersion(ctx *gin.Context, req *entity.Crea
 		return nil, errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error create article version: user id not found in context"))
 	}
 
+	err = u.validateArticleAccess(req.ArticleSerial, authorUsername, entity.GetContextRole(ctx), false)
+	if err != nil {
+		return nil, err
+	}
+
+	req.TagSerials, err = u.validateVersionTagSerials(req.TagSerials)
+	if err != nil {
+		return nil, err
+	}
+
 	latestVersionNumber, err := u.articleRepo.GetLatestVersionNumber(req.ArticleSerial)
 	if err != nil {
 		return nil, err
 	}
 
+	if req.BaseVersionSerial != "" {
+		baseVersion, err := u.getArticleVersion(req.ArticleSerial, req.BaseVersionSerial)
+		if err != nil {
+			return nil, err
+		}
+		if baseVersion.VersionNumber != latestVersionNumber {
+			return nil, errorutil.NewCustomError(errorutil.ErrConflict, fmt.Errorf("error create article version: base version '%s' (version number %d) is stale, latest version number is %d", baseVersion.Serial, baseVersion.VersionNumber, latestVersionNumber))
+		}
+	}
+
 	versionSerial, err := serialutil.GenerateId(versionSerialPrefix)
 	if err != nil {
 		return nil, fmt.Errorf("error create article version: error generate version serial: %s", err.Error())
@@ -305,23 +443,9 @@ func (u *articleUsecase) CreateArticleVersion(ctx *gin.Context, req *entity.Crea
 		Status:         entity.VersionStatusDraft.String(),
 	}
 
-	tx, err := u.articleRepo.GetDb().Begin()
-	if err != nil {
-		return nil, fmt.Errorf("error create article version: failed to begin transaction: %s", err.Error())
-	}
-
-	defer func() {
-		err = transactionutil.SettleTransaction(tx, err)
-	}()
-
-	err = u.articleRepo.InsertVersionTx(tx, version)
-	if err != nil {
-		return
-	}
-
-	err = u.articleRepo.InsertVersionTagsTx(tx, versionSerial, req.TagSerials)
+	err = u.insertVersion(version, req.TagSerials)
 	if err != nil {
-		return
+		return nil, err
 	}
 
 	resp = &entity.CreateArticleVersionResponse{
@@ -330,226 +454,1003 @@ func (u *articleUsecase) CreateArticleVersion(ctx *gin.Context, req *entity.Crea
 		Version:       version,
 	}
 
+	// suggestions are best effort, the version is already created
+	if req.SuggestTags {
+		suggestions, suggestErr := u.SuggestTags(&entity.SuggestTagsRequest{
+			Title:      req.Title,
+			Content:    req.Content,
+			TagSerials: req.TagSerials,
+		})
+		if suggestErr != nil {
+			log.Printf("[error] error suggest tags for version '%s': %v", resp.Version.Serial, suggestErr.Error())
+		} else {
+			resp.SuggestedTags = suggestions.Suggestions
+		}
+	}
+
 	return
 }
 
-func (u *articleUsecase) DeleteArticle(articleSerial string) error {
-	if articleSerial == "" {
-		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error delete article: article serial is mandatory"))
+// restore an old version by copying its title, content and tags into a new draft version
+func (u *articleUsecase) RestoreArticleVersion(ctx *gin.Context, req *entity.RestoreArticleVersionRequest) (*entity.CreateArticleVersionResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
 	}
 
-	var currPublishedVersion *entity.Version
-	publishedVersions, err := u.articleRepo.GetVersionsByQuery(&entity.GetVersionsByQueryRequest{
-		ArticleSerial: articleSerial,
-		Status:        entity.VersionStatusPublished.String(),
-	})
-	if err != nil {
-		return fmt.Errorf("error delete article: %s", err.Error())
-	}
-	if len(publishedVersions) > 0 {
-		currPublishedVersion = publishedVersions[0]
+	authorUsername := entity.GetContextUsername(ctx)
+	if authorUsername == "" {
+		return nil, errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error restore article version: user id not found in context"))
 	}
 
-	tx := u.transactionPkg.InitTransaction()
-	defer func() {
-		u.transactionPkg.SettleTransaction(tx, err)
-	}()
-
-	err = u.articleRepo.DeleteArticle(tx, articleSerial)
+	err := u.validateArticleAccess(req.ArticleSerial, authorUsername, entity.GetContextRole(ctx), false)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = u.articleRepo.DeleteVersionByArticleSerial(tx, articleSerial)
+	restoredVersion, err := u.getArticleVersion(req.ArticleSerial, req.VersionSerial)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if restoredVersion.Status == entity.VersionStatusDeleted.String() {
+		return nil, errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error restore article version: version '%s' is deleted", req.VersionSerial))
 	}
 
-	if currPublishedVersion != nil {
-		currPublishedVersionTagSerials := currPublishedVersion.TagSerials()
-		// decrement tag usage count the previous pubslihed version
-		err = u.tagRepo.DecrementUsageCount(tx, currPublishedVersionTagSerials)
-		if err != nil {
-			return err
-		}
-
-		// update the trending score
-		tagStats, err := u.tagRepo.GetTagStatsBySerials(tx, currPublishedVersion.TagSerials())
-		if err != nil {
-			return err
-		}
-
-		err = u.updateTrendingScore(tx, tagStats)
-		if err != nil {
-			return err
-		}
+	// the tags of an old version may be more than MAX_TAGS_PER_VERSION when it has been lowered since
+	tagSerials, err := u.validateVersionTagSerials(restoredVersion.TagSerials())
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
-}
+	latestVersionNumber, err := u.articleRepo.GetLatestVersionNumber(req.ArticleSerial)
+	if err != nil {
+		return nil, err
+	}
 
-func (u *articleUsecase) GetVersionsByArticleSerial(articleSerial string) (*entity.GetVersionsByArticleSerialResponse, error) {
-	if articleSerial == "" {
-		return nil, errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error get versions by article serial: article serial is mandatory"))
+	versionSerial, err := serialutil.GenerateId(versionSerialPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("error restore article version: error generate version serial: %s", err.Error())
+	}
+	version := &entity.Version{
+		Serial:                    versionSerial,
+		AuthorUsername:            authorUsername,
+		VersionNumber:             latestVersionNumber + 1,
+		ArticleSerial:             req.ArticleSerial,
+		Title:                     restoredVersion.Title,
+		Content:                   restoredVersion.Content,
+		Status:                    entity.VersionStatusDraft.String(),
+		RestoredFromVersionSerial: &restoredVersion.Serial,
+		Tags:                      restoredVersion.Tags,
 	}
 
-	versions, err := u.articleRepo.GetVersionsByQuery(&entity.GetVersionsByQueryRequest{
-		ArticleSerial: articleSerial,
-	})
+	err = u.insertVersion(version, tagSerials)
 	if err != nil {
 		return nil, err
 	}
 
-	return &entity.GetVersionsByArticleSerialResponse{
-		Versions: versions,
+	return &entity.CreateArticleVersionResponse{
+		ArticleSerial: req.ArticleSerial,
+		AuthorId:      authorUsername,
+		Version:       version,
 	}, nil
 }
 
-func (u *articleUsecase) GetVersionBySerial(serial string) (*entity.Version, error) {
-	if serial == "" {
-		return nil, errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error get version by serial: serial is mandatory"))
+// merge two versions that branch off the same base version into a new draft version,
+// content is merged line by line (three-way), title is merged as a whole and tags are united
+func (u *articleUsecase) MergeArticleVersions(ctx *gin.Context, req *entity.MergeArticleVersionsRequest) (*entity.MergeArticleVersionsResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
 	}
 
-	return u.articleRepo.GetVersionBySerial(serial)
-}
-
-// calculate the trending score using exponential decay with half-life set in config as TrendingScoreHalLifeDays
-func (u *articleUsecase) calculateTrendingScore(usageCount int, lastUpdatedAt time.Time) float32 {
-	if usageCount <= 0 {
-		return 0
+	authorUsername := entity.GetContextUsername(ctx)
+	if authorUsername == "" {
+		return nil, errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error merge article versions: user id not found in context"))
 	}
 
-	// age in days
-	ageDays := time.Since(lastUpdatedAt).Hours() / 24
-
-	// decay rate from half-life
-	lambda := math.Ln2 / u.cfg.TrendingScoreHalLifeDays
-
-	// decay formula
-	recencyFactor := math.Exp(-float64(lambda) * ageDays)
+	err := u.validateArticleAccess(req.ArticleSerial, authorUsername, entity.GetContextRole(ctx), false)
+	if err != nil {
+		return nil, err
+	}
 
-	return float32(usageCount) * float32(recencyFactor)
-}
+	baseVersion, err := u.getArticleVersion(req.ArticleSerial, req.BaseVersionSerial)
+	if err != nil {
+		return nil, err
+	}
+	oursVersion, err := u.getArticleVersion(req.ArticleSerial, req.OursVersionSerial)
+	if err != nil {
+		return nil, err
+	}
+	theirsVersion, err := u.getArticleVersion(req.ArticleSerial, req.TheirsVersionSerial)
+	if err != nil {
+		return nil, err
+	}
 
-func (u *articleUsecase) updateTrendingScore(tx *gorm.DB, tagStats []*entity.TagStat) error {
-	for _, tagStat := range tagStats {
-		newTrendingScore := u.calculateTrendingScore(int(tagStat.UsageCount), *tagStat.UsageCountUpdatedAt)
-		err := u.tagRepo.UpdateTagStat(tx, tagStat.TagSerial, newTrendingScore)
-		if err != nil {
-			return err
+	for _, version := range []*entity.Version{baseVersion, oursVersion, theirsVersion} {
+		if version.Status == entity.VersionStatusDeleted.String() {
+			return nil, errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error merge article versions: version '%s' is deleted", version.Serial))
+		}
+	}
+	for _, version := range []*entity.Version{oursVersion, theirsVersion} {
+		if version.VersionNumber <= baseVersion.VersionNumber {
+			return nil, errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error merge article versions: version '%s' is not newer than base version '%s'", version.Serial, baseVersion.Serial))
 		}
 	}
 
-	return nil
-}
+	err = u.validateDiffSize("merge article versions", baseVersion, oursVersion, theirsVersion)
+	if err != nil {
+		return nil, err
+	}
 
-func generatePairCombination(serials []string) [][]string {
-	var pairs [][]string
-	n := len(serials)
+	conflicts := []*entity.MergeConflict{}
 
-	for i := 0; i < n; i++ {
-		for j := i + 1; j < n; j++ {
-			// ensure the sequence, this used in tag_pair_stats
-			serial1 := serials[i]
-			serial2 := serials[j]
-			if serial1 > serial2 {
-				serial1, serial2 = serial2, serial1
-			}
+	title := oursVersion.Title
+	switch {
+	case oursVersion.Title == baseVersion.Title:
+		title = theirsVersion.Title
+	case theirsVersion.Title == baseVersion.Title, oursVersion.Title == theirsVersion.Title:
+		title = oursVersion.Title
+	default:
+		// title is kept from ours, the conflict is only reported
+		conflicts = append(conflicts, &entity.MergeConflict{
+			Field:     entity.MergeFieldTitle,
+			StartLine: 1,
+			EndLine:   1,
+			Base:      []string{baseVersion.Title},
+			Ours:      []string{oursVersion.Title},
+			Theirs:    []string{theirsVersion.Title},
+		})
+	}
+
+	mergedContent := diffutil.Merge3(
+		diffutil.SplitLines(baseVersion.Content),
+		diffutil.SplitLines(oursVersion.Content),
+		diffutil.SplitLines(theirsVersion.Content),
+		baseVersion.Serial, oursVersion.Serial, theirsVersion.Serial,
+	)
+	for _, c := range mergedContent.Conflicts {
+		conflicts = append(conflicts, &entity.MergeConflict{
+			Field:     entity.MergeFieldContent,
+			StartLine: c.StartLine,
+			EndLine:   c.EndLine,
+			Base:      c.Base,
+			Ours:      c.Ours,
+			Theirs:    c.Theirs,
+		})
+	}
 
-			pairs = append(pairs, []string{serial1, serial2})
+	tags := append([]*entity.Tag{}, oursVersion.Tags...)
+	tagMap := make(map[string]bool)
+	for _, tag := range oursVersion.Tags {
+		tagMap[tag.Serial] = true
+	}
+	for _, tag := range theirsVersion.Tags {
+		if !tagMap[tag.Serial] {
+			tags = append(tags, tag)
+			tagMap[tag.Serial] = true
 		}
 	}
 
-	return pairs
-}
+	// the union of the tags of both versions may be more than MAX_TAGS_PER_VERSION
+	tagSerials := []string{}
+	for _, tag := range tags {
+		tagSerials = append(tagSerials, tag.Serial)
+	}
+	tagSerials, err = u.validateVersionTagSerials(tagSerials)
+	if err != nil {
+		return nil, err
+	}
 
-// calculate tag relationship score using Positive Pointwise Mutual Information (PMI)
-func calculateTagRelationshipScore(tag1UsageCount, tag2UsageCount, pairUsageCount, totalPublishedArticle int) float32 {
-	// avoid divide by zero or invalid log
-	if tag1UsageCount == 0 || tag2UsageCount == 0 || pairUsageCount == 0 || totalPublishedArticle == 0 {
-		return 0
+	latestVersionNumber, err := u.articleRepo.GetLatestVersionNumber(req.ArticleSerial)
+	if err != nil {
+		return nil, err
 	}
 
-	// PMI = log2( (C(i,j) * N) / (C(i) * C(j)) )
-	pmi := math.Log2(float64(pairUsageCount) * float64(totalPublishedArticle) /
-		(float64(tag1UsageCount) * float64(tag2UsageCount)))
+	versionSerial, err := serialutil.GenerateId(versionSerialPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("error merge article versions: error generate version serial: %s", err.Error())
+	}
+	version := &entity.Version{
+		Serial:         versionSerial,
+		AuthorUsername: authorUsername,
+		VersionNumber:  latestVersionNumber + 1,
+		ArticleSerial:  req.ArticleSerial,
+		Title:          title,
+		Content:        mergedContent.Text(),
+		Status:         entity.VersionStatusDraft.String(),
+		Tags:           tags,
+	}
 
-	// PMI+ = max(PMI, 0)
-	if pmi < 0 {
-		return 0
+	err = u.insertVersion(version, tagSerials)
+	if err != nil {
+		return nil, err
 	}
 
-	return float32(pmi)
+	return &entity.MergeArticleVersionsResponse{
+		ArticleSerial: req.ArticleSerial,
+		AuthorId:      authorUsername,
+		Version:       version,
+		HasConflicts:  len(conflicts) > 0,
+		Conflicts:     conflicts,
+	}, nil
 }
 
-func (u *articleUsecase) getAllTagUsageCount(tx *gorm.DB, tagSerials []string) (map[string]int, map[string]int, error) {
-	mapTagUsageCount := make(map[string]int)
-	tagStats, err := u.tagRepo.GetTagStatsBySerials(tx, tagSerials)
+// insert version and its tags in one transaction
+func (u *articleUsecase) insertVersion(version *entity.Version, tagSerials []string) (err error) {
+	tx, err := u.articleRepo.GetDb().Begin()
 	if err != nil {
-		return nil, nil, err
-	}
-	for _, ts := range tagStats {
-		mapTagUsageCount[ts.TagSerial] = int(ts.UsageCount)
+		return fmt.Errorf("error insert version: failed to begin transaction: %s", err.Error())
 	}
 
-	mapTagPairUsageCount := make(map[string]int)
-	tagPairStats, err := u.tagRepo.GetTagPairStatsBySerials(tx, tagSerials)
+	defer func() {
+		err = transactionutil.SettleTransaction(tx, err)
+	}()
+
+	err = u.articleRepo.InsertVersionTx(tx, version)
 	if err != nil {
-		return nil, nil, err
+		return err
 	}
-	for _, tps := range tagPairStats {
-		mapTagPairUsageCount[fmt.Sprint(tps.Tag1Serial, "-", tps.Tag2Serial)] = int(tps.UsageCount)
+
+	err = u.articleRepo.InsertVersionTagsTx(tx, version.Serial, tagSerials)
+	if err != nil {
+		return err
 	}
 
-	return mapTagUsageCount, mapTagPairUsageCount, nil
+	return nil
 }
 
-func (u *articleUsecase) updateTagRelationshipScore(tx *gorm.DB, versionSerial string, tagSerials []string) error {
-	if len(tagSerials) < 2 {
-		return u.articleRepo.UpdateTagRelationshipScore(tx, versionSerial, 0)
+// replace the tags of a draft or the published version without creating a new version
+func (u *articleUsecase) UpdateVersionTags(ctx *gin.Context, req *entity.UpdateVersionTagsRequest) (*entity.Version, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	req.Username = entity.GetContextUsername(ctx)
+	req.Role = entity.GetContextRole(ctx)
+	if req.Username == "" || req.Role == "" {
+		return nil, errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error update version tags: user not found in context"))
 	}
 
-	totalPublishedVersion, err := u.articleRepo.GetTotalPublishedArticle(tx)
+	err := u.validateArticleAccess(req.ArticleSerial, req.Username, req.Role, false)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	mapTagUsageCount, mapTagPairUsageCount, err := u.getAllTagUsageCount(tx, tagSerials)
+	tagSerials, err := u.validateVersionTagSerials(req.TagSerials)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	version, err := u.getArticleVersion(req.ArticleSerial, req.VersionSerial)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.updateVersionTags(version, tagSerials, req.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.articleRepo.GetVersionBySerial(version.Serial)
+}
+
+func (u *articleUsecase) updateVersionTags(version *entity.Version, tagSerials []string, role string) (err error) {
+	tx := u.transactionPkg.InitTransaction()
+	defer func() {
+		err = u.transactionPkg.SettleTransaction(tx, err)
+	}()
+
+	// the status is checked on the locked row, the tag statistics depend on whether it is published
+	status, err := u.articleRepo.GetVersionStatusForUpdate(tx, version.Serial)
+	if err != nil {
+		return err
+	}
+
+	err = entity.ValidateVersionTagsEditable(status, role)
+	if err != nil {
+		return err
+	}
+
+	// the current tags are read after the lock, a concurrent edit may have replaced them since the version was loaded
+	currTagSerials, err := u.articleRepo.GetVersionTagSerials(tx, version.Serial)
+	if err != nil {
+		return err
+	}
+
+	err = u.articleRepo.ReplaceVersionTags(tx, version.Serial, tagSerials)
+	if err != nil {
+		return err
+	}
+
+	if !entity.IsPublishedStatus(status) {
+		return nil
+	}
+
+	removedTagSerials := subtractSerials(currTagSerials, tagSerials)
+	addedTagSerials := subtractSerials(tagSerials, currTagSerials)
+
+	if len(removedTagSerials) > 0 {
+		err = u.tagRepo.DecrementUsageCount(tx, removedTagSerials)
+		if err != nil {
+			return err
+		}
+	}
+	if len(addedTagSerials) > 0 {
+		err = u.tagRepo.IncrementUsageCount(tx, addedTagSerials)
+		if err != nil {
+			return err
+		}
+	}
+
+	// only the pairs that are removed or added change, the pairs of the kept tags stay the same
+	currPairs := generatePairCombination(currTagSerials)
+	newPairs := generatePairCombination(tagSerials)
+	for _, pair := range subtractPairs(currPairs, newPairs) {
+		err = u.tagRepo.DecrementTagPairStat(tx, pair[0], pair[1])
+		if err != nil {
+			return err
+		}
+	}
+	for _, pair := range subtractPairs(newPairs, currPairs) {
+		err = u.tagRepo.IncrementTagPairStat(tx, pair[0], pair[1])
+		if err != nil {
+			return err
+		}
+	}
+
+	affectedTagSerials := append(removedTagSerials, addedTagSerials...)
+	if len(affectedTagSerials) > 0 {
+		tagStats, err := u.tagRepo.GetTagStatsBySerials(tx, affectedTagSerials)
+		if err != nil {
+			return err
+		}
+
+		err = u.scorer.updateTrendingScore(tx, tagStats)
+		if err != nil {
+			return err
+		}
+	}
+
+	return u.scorer.updateTagRelationshipScore(tx, version.Serial, tagSerials)
+}
+
+// serials in from that are not in other
+func subtractSerials(from, other []string) []string {
+	mapOther := make(map[string]bool)
+	for _, serial := range other {
+		mapOther[serial] = true
+	}
+
+	result := []string{}
+	for _, serial := range from {
+		if !mapOther[serial] {
+			result = append(result, serial)
+		}
+	}
+
+	return result
+}
+
+// pairs in from that are not in other, pairs are generated by generatePairCombination so they are ordered
+func subtractPairs(from, other [][]string) [][]string {
+	mapOther := make(map[string]bool)
+	for _, pair := range other {
+		mapOther[fmt.Sprint(pair[0], "-", pair[1])] = true
+	}
+
+	result := [][]string{}
+	for _, pair := range from {
+		if !mapOther[fmt.Sprint(pair[0], "-", pair[1])] {
+			result = append(result, pair)
+		}
+	}
+
+	return result
+}
+
+func (u *articleUsecase) DeleteArticle(ctx *gin.Context, articleSerial string) (err error) {
+	if articleSerial == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error delete article: article serial is mandatory"))
+	}
+
+	// co-authors can not delete the article, only its owner
+	err = u.validateArticleAccess(articleSerial, entity.GetContextUsername(ctx), entity.GetContextRole(ctx), true)
+	if err != nil {
+		return err
+	}
+
+	versions, err := u.articleRepo.GetVersionsByQuery(&entity.GetVersionsByQueryRequest{
+		ArticleSerial: articleSerial,
+	})
+	if err != nil {
+		return fmt.Errorf("error delete article: %s", err.Error())
+	}
+
+	var currPublishedVersion *entity.Version
+	deletedEvents := []*entity.VersionStatusEvent{}
+	for _, version := range versions {
+		if entity.IsPublishedStatus(version.Status) {
+			currPublishedVersion = version
+		}
+		if version.Status != entity.VersionStatusDeleted.String() {
+			deletedEvents = append(deletedEvents, newVersionStatusEvent(version, entity.VersionStatusDeleted.String(), entity.GetContextUsername(ctx), entity.VersionStatusEventReasonArticleDeleted))
+		}
+	}
+
+	tx := u.transactionPkg.InitTransaction()
+	defer func() {
+		err = u.transactionPkg.SettleTransaction(tx, err)
+	}()
+
+	err = u.articleRepo.DeleteArticle(tx, articleSerial)
+	if err != nil {
+		return err
+	}
+
+	err = u.articleRepo.InsertVersionStatusEvents(tx, deletedEvents)
+	if err != nil {
+		return err
+	}
+
+	err = u.articleRepo.DeleteVersionByArticleSerial(tx, articleSerial)
+	if err != nil {
+		return err
+	}
+
+	err = u.articleRepo.CancelVersionSchedulesByArticleSerial(tx, articleSerial)
+	if err != nil {
+		return err
+	}
+
+	if currPublishedVersion != nil {
+		currPublishedVersionTagSerials := currPublishedVersion.TagSerials()
+		// decrement tag usage count the previous pubslihed version
+		err = u.tagRepo.DecrementUsageCount(tx, currPublishedVersionTagSerials)
+		if err != nil {
+			return err
+		}
+		err = u.scorer.decrementTagPairStats(tx, currPublishedVersionTagSerials)
+		if err != nil {
+			return err
+		}
+
+		// update the trending score
+		tagStats, err := u.tagRepo.GetTagStatsBySerials(tx, currPublishedVersionTagSerials)
+		if err != nil {
+			return err
+		}
+
+		err = u.scorer.updateTrendingScore(tx, tagStats)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolve tag serials, names or synonyms to the canonical tag serials, duplicates are removed.
+// Every value that is not found is listed in the error details
+func (u *articleUsecase) resolveTagSerials(values []string) ([]string, error) {
+	if len(values) == 0 {
+		return values, nil
+	}
+
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+	}
+	values = generalutil.SanitizeDuplicateSerials(values)
+
+	mapValueSerial, err := u.tagRepo.GetTagSerialsByValues(values)
+	if err != nil {
+		return nil, err
+	}
+
+	serials := []string{}
+	invalidValues := []string{}
+	for _, value := range values {
+		serial, ok := mapValueSerial[value]
+		if !ok {
+			invalidValues = append(invalidValues, value)
+			continue
+		}
+		serials = append(serials, serial)
+	}
+	if len(invalidValues) > 0 {
+		return nil, errorutil.NewCustomErrorWithDetails(errorutil.ErrBadRequest,
+			fmt.Errorf("error resolve tags: tags %q are not found", invalidValues),
+			&entity.InvalidTagsDetail{InvalidTagSerials: invalidValues})
+	}
+
+	// different names or synonyms can resolve to the same tag
+	return generalutil.SanitizeDuplicateSerials(serials), nil
+}
+
+// resolve the tags of a new version and make sure they do not exceed the max tags per version
+func (u *articleUsecase) validateVersionTagSerials(values []string) ([]string, error) {
+	serials, err := u.resolveTagSerials(values)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(serials) > u.cfg.MaxTagsPerVersion {
+		return nil, errorutil.NewCustomErrorWithDetails(errorutil.ErrBadRequest,
+			fmt.Errorf("error validate tags: version can have at most %d tags, got %d", u.cfg.MaxTagsPerVersion, len(serials)),
+			&entity.InvalidTagsDetail{MaxTags: u.cfg.MaxTagsPerVersion, TotalTags: len(serials)})
+	}
+
+	return serials, nil
+}
+
+// reverse the soft delete of an article, versions get back their status before deleted
+func (u *articleUsecase) RestoreDeletedArticle(ctx *gin.Context, articleSerial string) (err error) {
+	if articleSerial == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error restore deleted article: article serial is mandatory"))
+	}
+
+	article, err := u.articleRepo.GetArticleBySerial(articleSerial)
+	if err != nil {
+		return err
+	}
+	if article == nil {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error restore deleted article: article '%s' is not found", articleSerial))
+	}
+	if article.DeletedAt == nil {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error restore deleted article: article '%s' is not deleted", articleSerial))
+	}
+
+	versions, err := u.articleRepo.GetVersionsByQuery(&entity.GetVersionsByQueryRequest{
+		ArticleSerial: articleSerial,
+	})
+	if err != nil {
+		return fmt.Errorf("error restore deleted article: %s", err.Error())
+	}
+
+	mapVersionStatus, err := u.articleRepo.GetVersionStatusesBeforeDeleted(articleSerial)
+	if err != nil {
+		return err
+	}
+
+	tx := u.transactionPkg.InitTransaction()
+	defer func() {
+		err = u.transactionPkg.SettleTransaction(tx, err)
+	}()
+
+	err = u.articleRepo.RestoreArticle(tx, articleSerial)
+	if err != nil {
+		return err
+	}
+
+	var restoredPublishedVersion *entity.Version
+	restoredEvents := []*entity.VersionStatusEvent{}
+	for _, version := range versions {
+		if version.Status != entity.VersionStatusDeleted.String() {
+			continue
+		}
+
+		// version deleted before the history was recorded is restored as draft
+		status, ok := mapVersionStatus[version.Serial]
+		if !ok || status == entity.VersionStatusDeleted.String() {
+			status = entity.VersionStatusDraft.String()
+		}
+
+		err = u.articleRepo.RestoreVersion(tx, &entity.UpdateArticleVersionStatusRequest{
+			ArticleSerial: articleSerial,
+			VersionSerial: version.Serial,
+			NewStatus:     status,
+		})
+		if err != nil {
+			return err
+		}
+
+		restoredEvents = append(restoredEvents, newVersionStatusEvent(version, status, entity.GetContextUsername(ctx), entity.VersionStatusEventReasonArticleRestored))
+		if entity.IsPublishedStatus(status) {
+			restoredPublishedVersion = version
+		}
+	}
+
+	err = u.articleRepo.InsertVersionStatusEvents(tx, restoredEvents)
+	if err != nil {
+		return err
+	}
+
+	if restoredPublishedVersion != nil {
+		tagSerials := restoredPublishedVersion.TagSerials()
+
+		// increment tag usage count and tag pair for the published version
+		err = u.tagRepo.IncrementUsageCount(tx, tagSerials)
+		if err != nil {
+			return err
+		}
+		err = u.scorer.incrementTagPairStats(tx, tagSerials)
+		if err != nil {
+			return err
+		}
+
+		tagStats, err := u.tagRepo.GetTagStatsBySerials(tx, tagSerials)
+		if err != nil {
+			return err
+		}
+
+		err = u.scorer.updateTrendingScore(tx, tagStats)
+		if err != nil {
+			return err
+		}
+
+		err = u.scorer.updateTagRelationshipScore(tx, restoredPublishedVersion.Serial, tagSerials)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// hard delete articles that have been soft deleted longer than the retention period, triggered by worker
+func (u *articleUsecase) PurgeDeletedArticles() error {
+	for {
+		articleSerials, err := u.articleRepo.GetDeletedArticleSerials(u.cfg.DeletedArticleRetentionDays, purgeArticleBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(articleSerials) == 0 {
+			return nil
+		}
+
+		err = u.purgeArticles(articleSerials)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (u *articleUsecase) purgeArticles(articleSerials []string) (err error) {
+	tx := u.transactionPkg.InitTransaction()
+	defer func() {
+		err = u.transactionPkg.SettleTransaction(tx, err)
+	}()
+
+	return u.articleRepo.PurgeArticles(tx, articleSerials)
+}
+
+func (u *articleUsecase) GetArticleTimeline(req *entity.GetArticleTimelineRequest) (*entity.GetArticleTimelineResponse, error) {
+	if req.ArticleSerial == "" {
+		return nil, errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error get article timeline: article serial is mandatory"))
+	}
+
+	events, err := u.articleRepo.GetVersionStatusEvents(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.GetArticleTimelineResponse{
+		Events: events,
+	}, nil
+}
+
+func newVersionStatusEvent(version *entity.Version, toStatus, actorUsername, reason string) *entity.VersionStatusEvent {
+	event := &entity.VersionStatusEvent{
+		ArticleSerial: version.ArticleSerial,
+		VersionSerial: version.Serial,
+		VersionNumber: version.VersionNumber,
+		FromStatus:    version.Status,
+		ToStatus:      toStatus,
+		Reason:        reason,
+	}
+	if actorUsername != "" {
+		event.ActorUsername = &actorUsername
+	}
+
+	return event
+}
+
+func (u *articleUsecase) GetVersionsByArticleSerial(articleSerial string) (*entity.GetVersionsByArticleSerialResponse, error) {
+	if articleSerial == "" {
+		return nil, errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error get versions by article serial: article serial is mandatory"))
+	}
+
+	versions, err := u.articleRepo.GetVersionsByQuery(&entity.GetVersionsByQueryRequest{
+		ArticleSerial: articleSerial,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.GetVersionsByArticleSerialResponse{
+		Versions: versions,
+	}, nil
+}
+
+// rank other published articles by how related their tags are to the tags of the article's published version
+func (u *articleUsecase) GetRelatedArticles(req *entity.GetRelatedArticlesRequest) (*entity.GetRelatedArticlesResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	minScore := u.cfg.RelatedArticleMinScore
+	if req.MinScore != nil {
+		minScore = *req.MinScore
+	}
+
+	publishedVersions, err := u.articleRepo.GetVersionsByQuery(&entity.GetVersionsByQueryRequest{
+		ArticleSerial: req.ArticleSerial,
+		Status:        entity.VersionStatusPublished.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(publishedVersions) == 0 {
+		return nil, errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error get related articles: article '%s' has no published version", req.ArticleSerial))
+	}
+	sourceTagSerials := publishedVersions[0].TagSerials()
+
+	mapVersionTags, err := u.articleRepo.GetRelatedPublishedVersionTags(sourceTagSerials, req.ArticleSerial)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &entity.GetRelatedArticlesResponse{
+		Articles:   []*entity.RelatedArticle{},
+		Pagination: req.Pagination,
+	}
+	if len(mapVersionTags) == 0 {
+		return resp, nil
+	}
+
+	// collect every tag involved to load their usage counts at once
+	tagSerials := append([]string{}, sourceTagSerials...)
+	mapTagSerial := make(map[string]bool)
+	for _, serial := range sourceTagSerials {
+		mapTagSerial[serial] = true
+	}
+	for _, versionTagSerials := range mapVersionTags {
+		for _, serial := range versionTagSerials {
+			if !mapTagSerial[serial] {
+				mapTagSerial[serial] = true
+				tagSerials = append(tagSerials, serial)
+			}
+		}
+	}
+
+	totalPublishedArticle, err := u.articleRepo.GetTotalPublishedArticle(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	mapTagUsageCount, mapTagPairUsageCount, err := u.scorer.getAllTagUsageCount(nil, tagSerials)
+	if err != nil {
+		return nil, err
+	}
+
+	relatedArticles := []*entity.RelatedArticle{}
+	for versionSerial, versionTagSerials := range mapVersionTags {
+		score := calculateRelatedArticleScore(sourceTagSerials, versionTagSerials, mapTagUsageCount, mapTagPairUsageCount, totalPublishedArticle)
+		if score < minScore || score == 0 {
+			continue
+		}
+		relatedArticles = append(relatedArticles, &entity.RelatedArticle{
+			Score:   score,
+			Version: &entity.Version{Serial: versionSerial},
+		})
+	}
+	sort.Slice(relatedArticles, func(i, j int) bool {
+		if relatedArticles[i].Score != relatedArticles[j].Score {
+			return relatedArticles[i].Score > relatedArticles[j].Score
+		}
+		return relatedArticles[i].Version.Serial < relatedArticles[j].Version.Serial
+	})
+
+	if req.Pagination == nil {
+		req.Pagination = entity.ParseToPagination(0, 0)
+		req.Pagination.Validate()
+	}
+	req.Pagination.Total = len(relatedArticles)
+	offset := req.Pagination.GetOffset()
+	req.Pagination.SetPagination()
+	resp.Pagination = req.Pagination
+	if offset >= len(relatedArticles) {
+		return resp, nil
+	}
+	relatedArticles = relatedArticles[offset:min(offset+req.Pagination.PageSize, len(relatedArticles))]
+
+	// load the full versions of the requested page only
+	versionSerials := []string{}
+	for _, article := range relatedArticles {
+		versionSerials = append(versionSerials, article.Version.Serial)
+	}
+	versions, err := u.articleRepo.GetVersionsBySerials(versionSerials)
+	if err != nil {
+		return nil, err
+	}
+	mapVersion := make(map[string]*entity.Version)
+	for _, version := range versions {
+		mapVersion[version.Serial] = version
+	}
+	for _, article := range relatedArticles {
+		if version, ok := mapVersion[article.Version.Serial]; ok {
+			article.Version = version
+		}
+	}
+	resp.Articles = relatedArticles
+
+	return resp, nil
+}
+
+// score how related the candidate tags are to the source tags, every source tag takes its highest PMI+ with a candidate tag
+// and the result is averaged over the source tags. A shared tag weighs its self-information log2(N / C(i)),
+// which is the highest PMI the tag can have, so sharing a rare tag is worth more than sharing a common one
+func calculateRelatedArticleScore(sourceTagSerials, candidateTagSerials []string, mapTagUsageCount, mapTagPairUsageCount map[string]int, totalPublishedArticle int) float32 {
+	if len(sourceTagSerials) == 0 {
+		return 0
 	}
 
-	tagSerialPairCombination := generatePairCombination(tagSerials)
 	var totalScore float32
-	for _, pair := range tagSerialPairCombination {
-		tag1UsageCount, ok := mapTagUsageCount[pair[0]]
-		if !ok {
-			err = fmt.Errorf("error update tag relationship score: usage count tag '%s' is not found", pair[0])
-			return err
+	for _, sourceSerial := range sourceTagSerials {
+		var bestScore float32
+		for _, candidateSerial := range candidateTagSerials {
+			var score float32
+			if sourceSerial == candidateSerial {
+				usageCount := mapTagUsageCount[sourceSerial]
+				score = calculateTagRelationshipScore(usageCount, usageCount, usageCount, totalPublishedArticle)
+			} else {
+				pair := generatePairCombination([]string{sourceSerial, candidateSerial})[0]
+				score = calculateTagRelationshipScore(mapTagUsageCount[sourceSerial], mapTagUsageCount[candidateSerial],
+					mapTagPairUsageCount[fmt.Sprint(pair[0], "-", pair[1])], totalPublishedArticle)
+			}
+			bestScore = max(bestScore, score)
 		}
-		tag2UsageCount, ok := mapTagUsageCount[pair[1]]
-		if !ok {
-			err = fmt.Errorf("error update tag relationship score: usage count tag '%s' is not found", pair[0])
-			return err
+		totalScore += bestScore
+	}
+
+	return totalScore / float32(len(sourceTagSerials))
+}
+
+func (u *articleUsecase) GetVersionBySerial(serial string) (*entity.Version, error) {
+	if serial == "" {
+		return nil, errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error get version by serial: serial is mandatory"))
+	}
+
+	return u.articleRepo.GetVersionBySerial(serial)
+}
+
+func (u *articleUsecase) GetVersionDiff(req *entity.GetVersionDiffRequest) (*entity.GetVersionDiffResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	fromVersion, err := u.getArticleVersion(req.ArticleSerial, req.FromVersionSerial)
+	if err != nil {
+		return nil, err
+	}
+	toVersion, err := u.getArticleVersion(req.ArticleSerial, req.ToVersionSerial)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.validateDiffSize("get version diff", fromVersion, toVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	contextLines := diffutil.DefaultContextLines
+	if req.ContextLines != nil {
+		contextLines = *req.ContextLines
+	}
+
+	fromName := fmt.Sprintf("%s (v%d)", fromVersion.Serial, fromVersion.VersionNumber)
+	toName := fmt.Sprintf("%s (v%d)", toVersion.Serial, toVersion.VersionNumber)
+
+	return &entity.GetVersionDiffResponse{
+		ArticleSerial:     req.ArticleSerial,
+		FromVersionSerial: fromVersion.Serial,
+		FromVersionNumber: fromVersion.VersionNumber,
+		ToVersionSerial:   toVersion.Serial,
+		ToVersionNumber:   toVersion.VersionNumber,
+		Title:             diffutil.Compare(fromName, toName, fromVersion.Title, toVersion.Title, contextLines),
+		Content:           diffutil.Compare(fromName, toName, fromVersion.Content, toVersion.Content, contextLines),
+		Tags:              diffTags(fromVersion.Tags, toVersion.Tags),
+	}, nil
+}
+
+// the diff takes time in the product of the line counts, so versions longer than MAX_DIFF_LINES are not compared
+func (u *articleUsecase) validateDiffSize(operation string, versions ...*entity.Version) error {
+	for _, version := range versions {
+		lines := strings.Count(version.Content, "\n") + 1
+		if lines > u.cfg.MaxDiffLines {
+			return errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error %s: version '%s' has %d lines, more than the maximum %d lines", operation, version.Serial, lines, u.cfg.MaxDiffLines))
 		}
-		tagPairSerial := fmt.Sprint(pair[0], "-", pair[1])
-		tagPairUsageCount, ok := mapTagPairUsageCount[tagPairSerial]
-		if !ok {
-			err = fmt.Errorf("error update tag relationship score: usage count tag pair '%s' is not found", tagPairSerial)
+	}
+
+	return nil
+}
+
+// get version by serial and make sure it belongs to the article
+func (u *articleUsecase) getArticleVersion(articleSerial, versionSerial string) (*entity.Version, error) {
+	version, err := u.articleRepo.GetVersionBySerial(versionSerial)
+	if err != nil {
+		return nil, err
+	}
+	if version == nil || version.ArticleSerial != articleSerial {
+		return nil, errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error get article version: version '%s' is not found in article '%s'", versionSerial, articleSerial))
+	}
+
+	return version, nil
+}
+
+// get article by serial, return bad request error if it is not found or deleted
+func (u *articleUsecase) getArticle(articleSerial string) (*entity.Article, error) {
+	article, err := u.articleRepo.GetArticleBySerial(articleSerial)
+	if err != nil {
+		return nil, err
+	}
+	if article == nil || article.DeletedAt != nil {
+		return nil, errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error get article: article '%s' is not found", articleSerial))
+	}
+
+	return article, nil
+}
+
+// editors and admins can change every article, other roles only articles they own or,
+// unless ownerOnly, co-author
+func (u *articleUsecase) validateArticleAccess(articleSerial, username, role string, ownerOnly bool) error {
+	if entity.IsArticleModeratorRole(role) {
+		return nil
+	}
+
+	article, err := u.getArticle(articleSerial)
+	if err != nil {
+		return err
+	}
+	if article.OwnerUsername == username {
+		return nil
+	}
+
+	if !ownerOnly {
+		isCoauthor, err := u.articleRepo.IsArticleCoauthor(articleSerial, username)
+		if err != nil {
 			return err
 		}
+		if isCoauthor {
+			return nil
+		}
+		return errorutil.NewCustomError(errorutil.ErrForbidden, fmt.Errorf("error article access: user '%s' is not owner or co-author of article '%s'", username, articleSerial))
+	}
 
-		score := calculateTagRelationshipScore(tag1UsageCount, tag2UsageCount, tagPairUsageCount, totalPublishedVersion)
+	return errorutil.NewCustomError(errorutil.ErrForbidden, fmt.Errorf("error article access: user '%s' is not owner of article '%s'", username, articleSerial))
+}
+
+func diffTags(fromTags, toTags []*entity.Tag) *entity.TagDiff {
+	tagDiff := &entity.TagDiff{
+		Added:     []*entity.Tag{},
+		Removed:   []*entity.Tag{},
+		Unchanged: []*entity.Tag{},
+	}
 
-		totalScore += score
+	fromTagMap := make(map[string]bool)
+	for _, tag := range fromTags {
+		fromTagMap[tag.Serial] = true
+	}
+	toTagMap := make(map[string]bool)
+	for _, tag := range toTags {
+		toTagMap[tag.Serial] = true
 	}
 
-	finalScore := float32(totalScore) / float32(len(tagSerialPairCombination))
+	for _, tag := range fromTags {
+		if !toTagMap[tag.Serial] {
+			tagDiff.Removed = append(tagDiff.Removed, tag)
+		}
+	}
+	for _, tag := range toTags {
+		if fromTagMap[tag.Serial] {
+			tagDiff.Unchanged = append(tagDiff.Unchanged, tag)
+		} else {
+			tagDiff.Added = append(tagDiff.Added, tag)
+		}
+	}
 
-	return u.articleRepo.UpdateTagRelationshipScore(tx, versionSerial, finalScore)
+	return tagDiff
 }
 
-// update trending score for all tags that triggered by worker
+// update trending score for all tags that triggered by worker, the new scores are recorded as snapshots for the trend history
+// and the snapshots older than the retention period are deleted
 func (u *articleUsecase) UpdateTrendingScoreTags(pg *entity.Pagination) (err error) {
 	pg.SetToDefault()
 
@@ -559,12 +1460,13 @@ func (u *articleUsecase) UpdateTrendingScoreTags(pg *entity.Pagination) (err err
 	}()
 
 	for {
-		tagStats, err := u.tagRepo.GetTagStats(tx, pg)
+		var tagStats []*entity.TagStat
+		tagStats, err = u.tagRepo.GetTagStats(tx, pg)
 		if err != nil {
 			return err
 		}
 
-		err = u.updateTrendingScore(tx, tagStats)
+		err = u.scorer.updateTrendingScore(tx, tagStats)
 		if err != nil {
 			return err
 		}
@@ -575,5 +1477,301 @@ func (u *articleUsecase) UpdateTrendingScoreTags(pg *entity.Pagination) (err err
 		}
 	}
 
-	return nil
+	err = u.tagRepo.InsertTagTrendingSnapshots(tx)
+	if err != nil {
+		return err
+	}
+
+	return u.tagRepo.DeleteExpiredTagTrendingSnapshots(tx, u.cfg.TagSnapshotRetentionDays)
+}
+
+// recalculate the tag relationship score of every published version, page by page,
+// since the scores of older versions get stale as the tag stats and total published articles change
+func (u *articleUsecase) UpdateTagRelationshipScores() error {
+	totalPublishedArticle, err := u.articleRepo.GetTotalPublishedArticle(nil)
+	if err != nil {
+		return err
+	}
+
+	afterSerial := ""
+	for {
+		versionSerials, mapVersionTags, err := u.articleRepo.GetPublishedVersionTagSerials(afterSerial, tagRelationshipScoreBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(versionSerials) == 0 {
+			return nil
+		}
+
+		err = u.updateTagRelationshipScores(versionSerials, mapVersionTags, totalPublishedArticle)
+		if err != nil {
+			return err
+		}
+
+		afterSerial = versionSerials[len(versionSerials)-1]
+	}
+}
+
+// calculate the scores of a page of versions with the stats of all their tags loaded at once
+func (u *articleUsecase) updateTagRelationshipScores(versionSerials []string, mapVersionTags map[string][]string, totalPublishedArticle int) (err error) {
+	tagSerials := []string{}
+	for _, serials := range mapVersionTags {
+		tagSerials = append(tagSerials, serials...)
+	}
+	tagSerials = generalutil.SanitizeDuplicateSerials(tagSerials)
+
+	mapTagUsageCount, mapTagPairUsageCount, err := u.scorer.getAllTagUsageCount(nil, tagSerials)
+	if err != nil {
+		return err
+	}
+
+	mapVersionScore := make(map[string]float32)
+	for _, versionSerial := range versionSerials {
+		score, err := calculateVersionTagRelationshipScore(mapVersionTags[versionSerial], mapTagUsageCount, mapTagPairUsageCount, totalPublishedArticle)
+		if err != nil {
+			return fmt.Errorf("error version '%s': %s", versionSerial, err.Error())
+		}
+		mapVersionScore[versionSerial] = score
+	}
+
+	tx := u.transactionPkg.InitTransaction()
+	defer func() {
+		err = u.transactionPkg.SettleTransaction(tx, err)
+	}()
+
+	return u.articleRepo.UpdateTagRelationshipScores(tx, mapVersionScore)
+}
+
+func (u *articleUsecase) ScheduleArticleVersion(ctx *gin.Context, req *entity.ScheduleArticleVersionRequest) (resp *entity.ScheduleArticleVersionResponse, err error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	username := entity.GetContextUsername(ctx)
+	role := entity.GetContextRole(ctx)
+	if username == "" || role == "" {
+		return nil, errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error schedule article version: user not found in context"))
+	}
+
+	err = u.validateArticleAccess(req.ArticleSerial, username, role, false)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := u.getArticleVersion(req.ArticleSerial, req.VersionSerial)
+	if err != nil {
+		return nil, err
+	}
+	if version.Status == entity.VersionStatusDeleted.String() {
+		return nil, errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error schedule article version: version '%s' is deleted", req.VersionSerial))
+	}
+
+	// the status is checked when the schedule is applied, here the role must be allowed to make the transition
+	// the schedule applies: approved to published for publish and published to archived for unpublish
+	if req.PublishAt != nil {
+		err = entity.ValidateVersionStatusTransition(entity.VersionStatusApproved.String(), entity.VersionStatusPublished.String(), role)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if req.UnpublishAt != nil {
+		err = entity.ValidateVersionStatusTransition(entity.VersionStatusPublished.String(), entity.VersionStatusArchived.String(), role)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	mapActionScheduledAt := map[string]*time.Time{
+		entity.ScheduleActionPublish:   req.PublishAt,
+		entity.ScheduleActionUnpublish: req.UnpublishAt,
+	}
+
+	schedules := []*entity.VersionSchedule{}
+	for _, action := range []string{entity.ScheduleActionPublish, entity.ScheduleActionUnpublish} {
+		scheduledAt := mapActionScheduledAt[action]
+		if scheduledAt == nil {
+			continue
+		}
+
+		serial, err := serialutil.GenerateId(scheduleSerialPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("error schedule article version: error generate serial: %s", err.Error())
+		}
+
+		schedules = append(schedules, &entity.VersionSchedule{
+			Serial:        serial,
+			ArticleSerial: req.ArticleSerial,
+			VersionSerial: req.VersionSerial,
+			Action:        action,
+			ScheduledAt:   scheduledAt.UTC(),
+			Status:        entity.ScheduleStatusPending,
+			CreatedBy:     username,
+			CreatedByRole: role,
+		})
+	}
+
+	tx := u.transactionPkg.InitTransaction()
+	defer func() {
+		err = u.transactionPkg.SettleTransaction(tx, err)
+	}()
+
+	err = u.articleRepo.InsertVersionSchedules(tx, schedules)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.ScheduleArticleVersionResponse{
+		Schedules: schedules,
+	}, nil
+}
+
+func (u *articleUsecase) GetVersionSchedules(articleSerial string) (*entity.GetVersionSchedulesResponse, error) {
+	if articleSerial == "" {
+		return nil, errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error get version schedules: article serial is mandatory"))
+	}
+
+	schedules, err := u.articleRepo.GetVersionSchedulesByArticleSerial(articleSerial)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.GetVersionSchedulesResponse{
+		Schedules: schedules,
+	}, nil
+}
+
+func (u *articleUsecase) CancelVersionSchedule(ctx *gin.Context, articleSerial, scheduleSerial string) error {
+	if articleSerial == "" || scheduleSerial == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error cancel version schedule: article serial and schedule serial are mandatory"))
+	}
+
+	err := u.validateArticleAccess(articleSerial, entity.GetContextUsername(ctx), entity.GetContextRole(ctx), false)
+	if err != nil {
+		return err
+	}
+
+	return u.articleRepo.CancelVersionSchedule(nil, articleSerial, scheduleSerial)
+}
+
+// apply all due schedules that triggered by worker, a failed schedule is recorded and does not stop the others
+func (u *articleUsecase) ApplyDueVersionSchedules() error {
+	for {
+		schedules, err := u.articleRepo.GetDueVersionSchedules(dueVersionScheduleBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(schedules) == 0 {
+			return nil
+		}
+
+		for _, schedule := range schedules {
+			status := entity.ScheduleStatusDone
+			var errorMessage *string
+
+			err = u.applyVersionSchedule(schedule)
+			if err != nil {
+				status = entity.ScheduleStatusFailed
+				message := err.Error()
+				errorMessage = &message
+			}
+
+			err = u.articleRepo.UpdateVersionScheduleStatus(nil, schedule.Serial, status, errorMessage)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// apply the schedule through the same logic as manual status update, so tag statistics stay consistent,
+// the transition is validated with the role of the user who created the schedule
+func (u *articleUsecase) applyVersionSchedule(schedule *entity.VersionSchedule) error {
+	switch schedule.Action {
+	case entity.ScheduleActionPublish:
+		return u.updateArticleVersionStatus(&entity.UpdateArticleVersionStatusRequest{
+			ArticleSerial: schedule.ArticleSerial,
+			VersionSerial: schedule.VersionSerial,
+			NewStatus:     entity.VersionStatusPublished.String(),
+			Username:      schedule.CreatedBy,
+			Role:          schedule.CreatedByRole,
+			Reason:        entity.VersionStatusEventReasonScheduled,
+		})
+	case entity.ScheduleActionUnpublish:
+		version, err := u.articleRepo.GetVersionBySerial(schedule.VersionSerial)
+		if err != nil {
+			return err
+		}
+		if version == nil || !entity.IsPublishedStatus(version.Status) {
+			return fmt.Errorf("error apply version schedule: version '%s' is not published", schedule.VersionSerial)
+		}
+
+		return u.updateArticleVersionStatus(&entity.UpdateArticleVersionStatusRequest{
+			ArticleSerial: schedule.ArticleSerial,
+			VersionSerial: schedule.VersionSerial,
+			NewStatus:     entity.VersionStatusArchived.String(),
+			Username:      schedule.CreatedBy,
+			Role:          schedule.CreatedByRole,
+			Reason:        entity.VersionStatusEventReasonScheduled,
+		})
+	default:
+		return fmt.Errorf("error apply version schedule: action '%s' is unknown", schedule.Action)
+	}
+}
+
+func (u *articleUsecase) GetArticleCoauthors(articleSerial string) (*entity.GetArticleCoauthorsResponse, error) {
+	article, err := u.getArticle(articleSerial)
+	if err != nil {
+		return nil, err
+	}
+
+	coauthors, err := u.articleRepo.GetArticleCoauthors(articleSerial)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.GetArticleCoauthorsResponse{
+		OwnerUsername: article.OwnerUsername,
+		Coauthors:     coauthors,
+	}, nil
+}
+
+// add a co-author to the article, only the owner, editors and admins can add co-authors
+func (u *articleUsecase) AddArticleCoauthor(req *entity.AddArticleCoauthorRequest) error {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	err := u.validateArticleAccess(req.ArticleSerial, req.ActorUsername, req.Role, true)
+	if err != nil {
+		return err
+	}
+
+	article, err := u.getArticle(req.ArticleSerial)
+	if err != nil {
+		return err
+	}
+	if article.OwnerUsername == req.Username {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error add article co-author: '%s' is the owner of article '%s'", req.Username, req.ArticleSerial))
+	}
+
+	return u.articleRepo.InsertArticleCoauthor(nil, req.ArticleSerial, &entity.ArticleCoauthor{
+		Username:        req.Username,
+		AddedByUsername: req.ActorUsername,
+	})
+}
+
+// remove a co-author from the article, co-authors can remove themselves
+func (u *articleUsecase) RemoveArticleCoauthor(req *entity.RemoveArticleCoauthorRequest) error {
+	if req.ArticleSerial == "" || req.Username == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error remove article co-author: article serial and username are mandatory"))
+	}
+
+	if req.ActorUsername != req.Username {
+		err := u.validateArticleAccess(req.ArticleSerial, req.ActorUsername, req.Role, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	return u.articleRepo.DeleteArticleCoauthor(nil, req.ArticleSerial, req.Username)
 }
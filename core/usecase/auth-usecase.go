@@ -1,6 +1,7 @@
 package usecase
 
 import (
+	"crypto/subtle"
 	"errors"
 	"fmt"
 	"time"
@@ -9,41 +10,76 @@ import (
 
 	"article-versioning-api/config"
 	"article-versioning-api/core/entity"
+	"article-versioning-api/core/repository"
 	errorutil "article-versioning-api/utils/error"
+	jwkutil "article-versioning-api/utils/jwk"
+	serialutil "article-versioning-api/utils/serial"
 )
 
 type AuthUsecaseInterface interface {
-	CreateToken(user *entity.User) (tokenString string, err error)
-	VerifyToken(tokenString string) (*entity.User, error)
+	CreateToken(user *entity.User) (token *entity.AccessToken, err error)
+	VerifyToken(tokenString string) (*entity.TokenClaims, error)
+	GetJWKS() *jwkutil.JWKS
+	VerifyWorkerSecret(secret string) error
 }
 
 type authUsecase struct {
-	cfg *config.Config
+	userRepo  repository.UserRepositoryInterface
+	tokenRepo repository.TokenRepositoryInterface
+	keySet    *jwkutil.KeySet
+	cfg       *config.Config
 }
 
-func NewAuthUsecase(cfg *config.Config) AuthUsecaseInterface {
-	return &authUsecase{cfg}
+// NewAuthUsecase signs tokens with the signing key of keySet, keySet is only nil
+// for callers that do not issue or verify tokens (e.g. admin commands)
+func NewAuthUsecase(userRepo repository.UserRepositoryInterface, tokenRepo repository.TokenRepositoryInterface, keySet *jwkutil.KeySet, cfg *config.Config) AuthUsecaseInterface {
+	return &authUsecase{userRepo, tokenRepo, keySet, cfg}
 }
 
-func (u *authUsecase) CreateToken(user *entity.User) (tokenString string, err error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+const (
+	tokenIDLength = 21
+)
+
+func (u *authUsecase) CreateToken(user *entity.User) (*entity.AccessToken, error) {
+	tokenID, err := serialutil.GenerateRandomId(tokenIDLength)
+	if err != nil {
+		return nil, fmt.Errorf("error create token: error generate token id: %v", err.Error())
+	}
+	expiresAt := time.Now().Add(u.cfg.AccessTokenTTL)
+
+	claims := jwt.MapClaims{
 		entity.ContextUsername: user.Username,
 		entity.ContextRole:     user.Role,
-		"exp":                  time.Now().Add(time.Hour).Unix(),
-	})
+		"exp":                  expiresAt.Unix(),
+		"jti":                  tokenID,
+	}
+
+	if u.keySet == nil {
+		return nil, errors.New("error create token: signing keys are not loaded")
+	}
 
-	tokenString, err = token.SignedString([]byte(u.cfg.TokenSecret))
+	key := u.keySet.SigningKey()
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	tokenString, err := token.SignedString(key.PrivateKey)
 	if err != nil {
-		return "", fmt.Errorf("error create token: %v", err.Error())
+		return nil, fmt.Errorf("error create token: %v", err.Error())
 	}
 
-	return tokenString, nil
+	return &entity.AccessToken{
+		Token:     tokenString,
+		ID:        tokenID,
+		ExpiresAt: expiresAt,
+	}, nil
 }
 
-func (u *authUsecase) VerifyToken(tokenString string) (*entity.User, error) {
-	token, err := jwt.Parse(tokenString, func(tokenString *jwt.Token) (interface{}, error) {
-		return []byte(u.cfg.TokenSecret), nil
-	})
+func (u *authUsecase) VerifyToken(tokenString string) (*entity.TokenClaims, error) {
+	if u.keySet == nil {
+		return nil, errorutil.NewCustomError(errorutil.ErrUnauthorized, errors.New("error verify token: signing keys are not loaded"))
+	}
+
+	parser := &jwt.Parser{ValidMethods: u.keySet.Algorithms()}
+	token, err := parser.Parse(tokenString, u.getVerifyKey)
 	if err != nil {
 		return nil, errorutil.NewCustomError(errorutil.ErrUnauthorized, fmt.Errorf("error verify token: %v", err.Error()))
 	}
@@ -51,17 +87,80 @@ func (u *authUsecase) VerifyToken(tokenString string) (*entity.User, error) {
 		return nil, errorutil.NewCustomError(errorutil.ErrUnauthorized, errors.New("error verify token: token is invalid"))
 	}
 
-	username, ok := token.Claims.(jwt.MapClaims)[entity.ContextUsername].(string)
+	claims := token.Claims.(jwt.MapClaims)
+	username, ok := claims[entity.ContextUsername].(string)
 	if !ok {
 		return nil, errorutil.NewCustomError(errorutil.ErrUnauthorized, errors.New("error verify token: user name is not found in token"))
 	}
-	role, ok := token.Claims.(jwt.MapClaims)[entity.ContextRole].(string)
+	tokenID, ok := claims["jti"].(string)
+	if !ok || tokenID == "" {
+		return nil, errorutil.NewCustomError(errorutil.ErrUnauthorized, errors.New("error verify token: token id is not found in token"))
+	}
+	exp, ok := claims["exp"].(float64)
 	if !ok {
-		return nil, errorutil.NewCustomError(errorutil.ErrUnauthorized, errors.New("error verify token: role is not found in token"))
+		return nil, errorutil.NewCustomError(errorutil.ErrUnauthorized, errors.New("error verify token: expiry is not found in token"))
 	}
 
-	return &entity.User{
-		Username: username,
-		Role:     role,
+	revoked, err := u.tokenRepo.IsTokenRevoked(tokenID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errorutil.NewCustomError(errorutil.ErrUnauthorized, errors.New("error verify token: token is revoked"))
+	}
+
+	// the role claim is ignored since the role may be changed by an admin, the current role is used
+	user, err := u.userRepo.GetUserByUsername(username)
+	if err != nil {
+		return nil, errorutil.NewCustomError(errorutil.ErrUnauthorized, fmt.Errorf("error verify token: %v", err.Error()))
+	}
+	if !user.IsActive {
+		return nil, errorutil.NewCustomError(errorutil.ErrUnauthorized, errors.New("error verify token: user is deactivated"))
+	}
+
+	return &entity.TokenClaims{
+		Username:  username,
+		Role:      user.Role,
+		ID:        tokenID,
+		ExpiresAt: time.Unix(int64(exp), 0),
 	}, nil
 }
+
+// getVerifyKey returns the key of the token kid, the token algorithm must be the algorithm of the key
+// so a token can not be verified with a key of other type (e.g. HS256 signed with RSA public key)
+func (u *authUsecase) getVerifyKey(token *jwt.Token) (interface{}, error) {
+	kid, ok := token.Header["kid"].(string)
+	if !ok || kid == "" {
+		return nil, errors.New("key id is not found in token")
+	}
+	key, ok := u.keySet.Key(kid)
+	if !ok {
+		return nil, fmt.Errorf("key '%s' is unknown", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("signing method %s is not allowed for key '%s'", token.Method.Alg(), kid)
+	}
+
+	return key.PublicKey, nil
+}
+
+// GetJWKS returns the public keys to verify tokens
+func (u *authUsecase) GetJWKS() *jwkutil.JWKS {
+	if u.keySet == nil {
+		return &jwkutil.JWKS{Keys: []*jwkutil.JWK{}}
+	}
+
+	return u.keySet.JWKS()
+}
+
+// VerifyWorkerSecret checks the secret of the worker, the worker endpoints are disabled when WORKER_SECRET is not set
+func (u *authUsecase) VerifyWorkerSecret(secret string) error {
+	if u.cfg.WorkerSecret == "" {
+		return errorutil.NewCustomError(errorutil.ErrUnauthorized, errors.New("error verify worker secret: worker secret is not configured"))
+	}
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(u.cfg.WorkerSecret)) != 1 {
+		return errorutil.NewCustomError(errorutil.ErrUnauthorized, errors.New("error verify worker secret: secret is invalid"))
+	}
+
+	return nil
+}
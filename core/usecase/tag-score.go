@@ -0,0 +1,189 @@
+package usecase
+
+import (
+	"article-versioning-api/config"
+	"article-versioning-api/core/entity"
+	"article-versioning-api/core/repository"
+	"fmt"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// tagScorer keeps the tag trending and relationship scores up to date, shared by the article and tag usecases
+type tagScorer struct {
+	articleRepo repository.ArticleRepositoryInterface
+	tagRepo     repository.TagRepositoryInterface
+	cfg         *config.Config
+}
+
+// calculate the trending score using exponential decay with half-life set in config as TrendingScoreHalLifeDays
+func (s *tagScorer) calculateTrendingScore(usageCount int, lastUpdatedAt time.Time) float32 {
+	if usageCount <= 0 {
+		return 0
+	}
+
+	// age in days
+	ageDays := time.Since(lastUpdatedAt).Hours() / 24
+
+	// decay rate from half-life
+	lambda := math.Ln2 / s.cfg.TrendingScoreHalLifeDays
+
+	// decay formula
+	recencyFactor := math.Exp(-float64(lambda) * ageDays)
+
+	return float32(usageCount) * float32(recencyFactor)
+}
+
+func (s *tagScorer) updateTrendingScore(tx *gorm.DB, tagStats []*entity.TagStat) error {
+	for _, tagStat := range tagStats {
+		newTrendingScore := s.calculateTrendingScore(int(tagStat.UsageCount), *tagStat.UsageCountUpdatedAt)
+		err := s.tagRepo.UpdateTagStat(tx, tagStat.TagSerial, newTrendingScore)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func generatePairCombination(serials []string) [][]string {
+	var pairs [][]string
+	n := len(serials)
+
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			// ensure the sequence, this used in tag_pair_stats
+			serial1 := serials[i]
+			serial2 := serials[j]
+			if serial1 > serial2 {
+				serial1, serial2 = serial2, serial1
+			}
+
+			pairs = append(pairs, []string{serial1, serial2})
+		}
+	}
+
+	return pairs
+}
+
+// increment the pair stats of every pair of the tags, done whenever a version with the tags is published
+func (s *tagScorer) incrementTagPairStats(tx *gorm.DB, tagSerials []string) error {
+	for _, pair := range generatePairCombination(tagSerials) {
+		err := s.tagRepo.IncrementTagPairStat(tx, pair[0], pair[1])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// decrement the pair stats of every pair of the tags, done whenever a version with the tags is no longer published
+func (s *tagScorer) decrementTagPairStats(tx *gorm.DB, tagSerials []string) error {
+	for _, pair := range generatePairCombination(tagSerials) {
+		err := s.tagRepo.DecrementTagPairStat(tx, pair[0], pair[1])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// calculate tag relationship score using Positive Pointwise Mutual Information (PMI)
+func calculateTagRelationshipScore(tag1UsageCount, tag2UsageCount, pairUsageCount, totalPublishedArticle int) float32 {
+	// avoid divide by zero or invalid log
+	if tag1UsageCount == 0 || tag2UsageCount == 0 || pairUsageCount == 0 || totalPublishedArticle == 0 {
+		return 0
+	}
+
+	// PMI = log2( (C(i,j) * N) / (C(i) * C(j)) )
+	pmi := math.Log2(float64(pairUsageCount) * float64(totalPublishedArticle) /
+		(float64(tag1UsageCount) * float64(tag2UsageCount)))
+
+	// PMI+ = max(PMI, 0)
+	if pmi < 0 {
+		return 0
+	}
+
+	return float32(pmi)
+}
+
+func (s *tagScorer) getAllTagUsageCount(tx *gorm.DB, tagSerials []string) (map[string]int, map[string]int, error) {
+	mapTagUsageCount := make(map[string]int)
+	tagStats, err := s.tagRepo.GetTagStatsBySerials(tx, tagSerials)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, ts := range tagStats {
+		mapTagUsageCount[ts.TagSerial] = int(ts.UsageCount)
+	}
+
+	mapTagPairUsageCount := make(map[string]int)
+	tagPairStats, err := s.tagRepo.GetTagPairStatsBySerials(tx, tagSerials)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, tps := range tagPairStats {
+		mapTagPairUsageCount[fmt.Sprint(tps.Tag1Serial, "-", tps.Tag2Serial)] = int(tps.UsageCount)
+	}
+
+	return mapTagUsageCount, mapTagPairUsageCount, nil
+}
+
+func (s *tagScorer) updateTagRelationshipScore(tx *gorm.DB, versionSerial string, tagSerials []string) error {
+	if len(tagSerials) < 2 {
+		return s.articleRepo.UpdateTagRelationshipScore(tx, versionSerial, 0)
+	}
+
+	totalPublishedVersion, err := s.articleRepo.GetTotalPublishedArticle(tx)
+	if err != nil {
+		return err
+	}
+
+	mapTagUsageCount, mapTagPairUsageCount, err := s.getAllTagUsageCount(tx, tagSerials)
+	if err != nil {
+		return err
+	}
+
+	finalScore, err := calculateVersionTagRelationshipScore(tagSerials, mapTagUsageCount, mapTagPairUsageCount, totalPublishedVersion)
+	if err != nil {
+		return err
+	}
+
+	return s.articleRepo.UpdateTagRelationshipScore(tx, versionSerial, finalScore)
+}
+
+// calculate the tag relationship score of a version as the average PMI+ of all its tag pairs,
+// the usage count maps are keyed by tag serial and by "tag1-tag2" as returned by getAllTagUsageCount
+func calculateVersionTagRelationshipScore(tagSerials []string, mapTagUsageCount, mapTagPairUsageCount map[string]int, totalPublishedVersion int) (float32, error) {
+	tagSerialPairCombination := generatePairCombination(tagSerials)
+	if len(tagSerialPairCombination) == 0 {
+		return 0, nil
+	}
+
+	var totalScore float32
+	for _, pair := range tagSerialPairCombination {
+		tag1UsageCount, ok := mapTagUsageCount[pair[0]]
+		if !ok {
+			return 0, fmt.Errorf("error update tag relationship score: usage count tag '%s' is not found", pair[0])
+		}
+		tag2UsageCount, ok := mapTagUsageCount[pair[1]]
+		if !ok {
+			return 0, fmt.Errorf("error update tag relationship score: usage count tag '%s' is not found", pair[1])
+		}
+		tagPairSerial := fmt.Sprint(pair[0], "-", pair[1])
+		tagPairUsageCount, ok := mapTagPairUsageCount[tagPairSerial]
+		if !ok {
+			return 0, fmt.Errorf("error update tag relationship score: usage count tag pair '%s' is not found", tagPairSerial)
+		}
+
+		score := calculateTagRelationshipScore(tag1UsageCount, tag2UsageCount, tagPairUsageCount, totalPublishedVersion)
+
+		totalScore += score
+	}
+
+	return totalScore / float32(len(tagSerialPairCombination)), nil
+}
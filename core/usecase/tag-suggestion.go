@@ -0,0 +1,212 @@
+package usecase
+
+import (
+	"article-versioning-api/core/entity"
+	"math"
+	"sort"
+)
+
+const (
+	// lexemes in the title count more than lexemes in the content
+	titleLexemeWeight = 2
+)
+
+// suggest existing tags for the title and content, scored by TF-IDF against the published versions of every tag
+// and by co-occurrence with the tags already picked
+func (u *articleUsecase) SuggestTags(req *entity.SuggestTagsRequest) (*entity.SuggestTagsResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	pickedTagSerials, err := u.resolveTagSerials(req.TagSerials)
+	if err != nil {
+		return nil, err
+	}
+
+	titleLexemes, err := u.articleRepo.GetTextLexemes(req.Title)
+	if err != nil {
+		return nil, err
+	}
+	contentLexemes, err := u.articleRepo.GetTextLexemes(req.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	mapLexemeFrequency := make(map[string]int)
+	for lexeme, occurrence := range titleLexemes {
+		mapLexemeFrequency[lexeme] += titleLexemeWeight * occurrence
+	}
+	for lexeme, occurrence := range contentLexemes {
+		mapLexemeFrequency[lexeme] += occurrence
+	}
+	lexemes := []string{}
+	for lexeme := range mapLexemeFrequency {
+		lexemes = append(lexemes, lexeme)
+	}
+
+	lexemeStats, err := u.articleRepo.GetTagLexemeStats(lexemes)
+	if err != nil {
+		return nil, err
+	}
+	mapContentScore := calculateTagContentScores(mapLexemeFrequency, lexemeStats)
+
+	mapCooccurrenceScore, err := u.getTagCooccurrenceScores(pickedTagSerials)
+	if err != nil {
+		return nil, err
+	}
+
+	// co-occurrence only weighs in when there are picked tags
+	cooccurrenceWeight := float32(0)
+	if len(pickedTagSerials) > 0 {
+		cooccurrenceWeight = u.cfg.TagSuggestionCooccurrenceWeight
+	}
+
+	mapPicked := make(map[string]bool)
+	for _, serial := range pickedTagSerials {
+		mapPicked[serial] = true
+	}
+
+	mapSuggestion := make(map[string]*entity.TagSuggestion)
+	getSuggestion := func(serial string) *entity.TagSuggestion {
+		if _, ok := mapSuggestion[serial]; !ok {
+			mapSuggestion[serial] = &entity.TagSuggestion{Tag: &entity.Tag{Serial: serial}}
+		}
+		return mapSuggestion[serial]
+	}
+	for serial, score := range mapContentScore {
+		if !mapPicked[serial] {
+			getSuggestion(serial).ContentScore = score
+		}
+	}
+	for serial, score := range mapCooccurrenceScore {
+		if !mapPicked[serial] {
+			getSuggestion(serial).CooccurrenceScore = score
+		}
+	}
+
+	suggestions := []*entity.TagSuggestion{}
+	for _, suggestion := range mapSuggestion {
+		suggestion.Score = (1-cooccurrenceWeight)*suggestion.ContentScore + cooccurrenceWeight*suggestion.CooccurrenceScore
+		if suggestion.Score > 0 {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].Tag.Serial < suggestions[j].Tag.Serial
+	})
+	if len(suggestions) > req.Limit {
+		suggestions = suggestions[:req.Limit]
+	}
+
+	tagSerials := []string{}
+	for _, suggestion := range suggestions {
+		tagSerials = append(tagSerials, suggestion.Tag.Serial)
+	}
+	tags, err := u.tagRepo.GetTagsBySerials(tagSerials)
+	if err != nil {
+		return nil, err
+	}
+	mapTag := make(map[string]*entity.Tag)
+	for _, tag := range tags {
+		mapTag[tag.Serial] = tag
+	}
+	for _, suggestion := range suggestions {
+		if tag, ok := mapTag[suggestion.Tag.Serial]; ok {
+			suggestion.Tag = tag
+		}
+	}
+
+	return &entity.SuggestTagsResponse{
+		Suggestions: suggestions,
+	}, nil
+}
+
+// rebuild the lexeme counts of the published versions used by the content score, triggered by worker
+func (u *articleUsecase) UpdateLexemeStats() (err error) {
+	tx := u.transactionPkg.InitTransaction()
+	defer func() {
+		err = u.transactionPkg.SettleTransaction(tx, err)
+	}()
+
+	return u.articleRepo.RebuildLexemeStats(tx)
+}
+
+// score every tag by the share of the text's TF-IDF weight that its published versions cover:
+//
+//	weight(w) = (1 + ln tf(w)) * (ln((N + 1) / (df(w) + 1)) + 1)
+//	score(t)  = sum of weight(w) * df(t, w) / df(t) / sum of weight(w)
+//
+// where N is the number of published versions, df(w) the ones containing lexeme w,
+// df(t) the ones with tag t and df(t, w) the ones with tag t containing lexeme w
+func calculateTagContentScores(mapLexemeFrequency map[string]int, stats *entity.TagLexemeStats) map[string]float32 {
+	mapScore := make(map[string]float32)
+
+	mapLexemeWeight := make(map[string]float64)
+	var totalWeight float64
+	for lexeme, frequency := range mapLexemeFrequency {
+		if frequency <= 0 {
+			continue
+		}
+		idf := math.Log(float64(stats.TotalVersion+1)/float64(stats.LexemeVersionCount[lexeme]+1)) + 1
+		weight := (1 + math.Log(float64(frequency))) * idf
+
+		mapLexemeWeight[lexeme] = weight
+		totalWeight += weight
+	}
+	if totalWeight == 0 {
+		return mapScore
+	}
+
+	for tagSerial, mapTagLexeme := range stats.TagLexemeVersionCount {
+		tagVersionCount := stats.TagVersionCount[tagSerial]
+		if tagVersionCount == 0 {
+			continue
+		}
+
+		var score float64
+		for lexeme, versionCount := range mapTagLexeme {
+			score += mapLexemeWeight[lexeme] * float64(versionCount) / float64(tagVersionCount)
+		}
+		mapScore[tagSerial] = float32(score / totalWeight)
+	}
+
+	return mapScore
+}
+
+// score every tag by how likely it is used together with the picked tags, the average of P(tag | picked tag) = C(picked, tag) / C(picked)
+func (u *articleUsecase) getTagCooccurrenceScores(pickedTagSerials []string) (map[string]float32, error) {
+	mapScore := make(map[string]float32)
+	if len(pickedTagSerials) == 0 {
+		return mapScore, nil
+	}
+
+	tagStats, err := u.tagRepo.GetTagStatsBySerials(nil, pickedTagSerials)
+	if err != nil {
+		return nil, err
+	}
+	mapUsageCount := make(map[string]int)
+	for _, ts := range tagStats {
+		mapUsageCount[ts.TagSerial] = int(ts.UsageCount)
+	}
+
+	tagPairStats, err := u.tagRepo.GetTagPairStatsByAnySerials(pickedTagSerials)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tps := range tagPairStats {
+		for _, pair := range [][]string{{tps.Tag1Serial, tps.Tag2Serial}, {tps.Tag2Serial, tps.Tag1Serial}} {
+			pickedSerial, tagSerial := pair[0], pair[1]
+			usageCount, ok := mapUsageCount[pickedSerial]
+			if !ok || usageCount == 0 {
+				continue
+			}
+			mapScore[tagSerial] += float32(min(tps.UsageCount, usageCount)) / float32(usageCount) / float32(len(pickedTagSerials))
+		}
+	}
+
+	return mapScore, nil
+}
@@ -9,22 +9,37 @@ import (
 	transactionutil "article-versioning-api/utils/transaction"
 	"errors"
 	"fmt"
+	"strings"
 )
 
 type tagUsecase struct {
 	tagRepo        repository.TagRepositoryInterface
+	articleRepo    repository.ArticleRepositoryInterface
 	transactionPkg transactionutil.Transaction
 	cfg            *config.Config
+	scorer         tagScorer
 }
 
 type TagUsecaseInterface interface {
 	CreateTag(req *entity.CreateTagRequest) (serial string, err error)
 	GetTags(req *entity.GetTagsRequest) (*entity.GetTagsResponse, error)
 	GetTagBySerial(serial string) (*entity.TagDetail, error)
+	GetTagChildren(serial string) (*entity.GetTagChildrenResponse, error)
+	UpdateTagParent(req *entity.UpdateTagParentRequest) (err error)
+	RenameTag(req *entity.RenameTagRequest) (err error)
+	DeleteTag(serial string) (err error)
+	MergeTag(req *entity.MergeTagRequest) (err error)
+	CreateTagSynonym(req *entity.CreateTagSynonymRequest) error
+	GetTagSynonyms(serial string) (*entity.GetTagSynonymsResponse, error)
+	DeleteTagSynonym(serial, name string) error
+	GetTrendingTags(req *entity.GetTrendingTagsRequest) (*entity.GetTrendingTagsResponse, error)
+	GetTagTrend(req *entity.GetTagTrendRequest) (*entity.GetTagTrendResponse, error)
+	RebuildTagStats() (err error)
+	GetTagGraph(req *entity.GetTagGraphRequest) (*entity.TagGraph, error)
 }
 
-func NewTagUsecase(tagRepo repository.TagRepositoryInterface, transactionPkg transactionutil.Transaction, cfg *config.Config) TagUsecaseInterface {
-	return &tagUsecase{tagRepo, transactionPkg, cfg}
+func NewTagUsecase(tagRepo repository.TagRepositoryInterface, articleRepo repository.ArticleRepositoryInterface, transactionPkg transactionutil.Transaction, cfg *config.Config) TagUsecaseInterface {
+	return &tagUsecase{tagRepo, articleRepo, transactionPkg, cfg, tagScorer{articleRepo, tagRepo, cfg}}
 }
 
 const (
@@ -36,17 +51,32 @@ func (u *tagUsecase) CreateTag(req *entity.CreateTagRequest) (serial string, err
 		return "", err
 	}
 
+	err = u.validateTagNameAvailable(req.Name, "")
+	if err != nil {
+		return "", err
+	}
+
+	if req.ParentSerial != nil {
+		_, err = u.getTag(*req.ParentSerial)
+		if err != nil {
+			return "", err
+		}
+	}
+
 	serial, err = serialutil.GenerateId(tagSerialPrefix)
 	if err != nil {
 		return "", fmt.Errorf("error create tag: error generate serial: %s", err.Error())
 	}
 
 	tx := u.transactionPkg.InitTransaction()
-	defer u.transactionPkg.SettleTransaction(tx, err)
+	defer func() {
+		err = u.transactionPkg.SettleTransaction(tx, err)
+	}()
 
 	err = u.tagRepo.InsertTag(&entity.Tag{
-		Serial: serial,
-		Name:   req.Name,
+		Serial:       serial,
+		Name:         req.Name,
+		ParentSerial: req.ParentSerial,
 	}, tx)
 	if err != nil {
 		return "", err
@@ -83,3 +113,457 @@ func (u *tagUsecase) GetTagBySerial(serial string) (*entity.TagDetail, error) {
 
 	return u.tagRepo.GetTagBySerial(serial)
 }
+
+// get tag by serial, return bad request error if it is not found
+func (u *tagUsecase) getTag(serial string) (*entity.TagDetail, error) {
+	tag, err := u.tagRepo.GetTagBySerial(serial)
+	if err != nil {
+		return nil, err
+	}
+	if tag.Serial == "" {
+		return nil, errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error get tag: tag '%s' is not found", serial))
+	}
+
+	return tag, nil
+}
+
+func (u *tagUsecase) GetTagChildren(serial string) (*entity.GetTagChildrenResponse, error) {
+	if serial == "" {
+		return nil, errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error get tag children: serial is mandatory"))
+	}
+
+	_, err := u.getTag(serial)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := u.tagRepo.GetTagChildren(serial)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.GetTagChildrenResponse{
+		Tags: tags,
+	}, nil
+}
+
+// move a tag (with its descendants) under another tag or to the root
+func (u *tagUsecase) UpdateTagParent(req *entity.UpdateTagParentRequest) (err error) {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	_, err = u.getTag(req.TagSerial)
+	if err != nil {
+		return err
+	}
+	if req.ParentSerial != nil {
+		_, err = u.getTag(*req.ParentSerial)
+		if err != nil {
+			return err
+		}
+	}
+
+	tx := u.transactionPkg.InitTransaction()
+	defer func() {
+		err = u.transactionPkg.SettleTransaction(tx, err)
+	}()
+
+	err = u.tagRepo.LockTagHierarchy(tx)
+	if err != nil {
+		return err
+	}
+
+	if req.ParentSerial != nil {
+		descendantSerials, err := u.tagRepo.GetTagDescendantSerials(tx, req.TagSerial)
+		if err != nil {
+			return err
+		}
+		for _, serial := range descendantSerials {
+			if serial == *req.ParentSerial {
+				return errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error update tag parent: tag '%s' is a descendant of tag '%s'", serial, req.TagSerial))
+			}
+		}
+	}
+
+	return u.tagRepo.UpdateTagParent(tx, req.TagSerial, req.ParentSerial)
+}
+
+func (u *tagUsecase) RenameTag(req *entity.RenameTagRequest) (err error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	_, err = u.getTag(req.TagSerial)
+	if err != nil {
+		return err
+	}
+
+	err = u.validateTagNameAvailable(req.Name, req.TagSerial)
+	if err != nil {
+		return err
+	}
+
+	return u.tagRepo.UpdateTagName(nil, req.TagSerial, req.Name)
+}
+
+// delete a tag from every version, its children are moved to its parent
+// and the relationship scores of the published versions that used it are recomputed
+func (u *tagUsecase) DeleteTag(serial string) (err error) {
+	if serial == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error delete tag: serial is mandatory"))
+	}
+
+	tag, err := u.getTag(serial)
+	if err != nil {
+		return err
+	}
+
+	tx := u.transactionPkg.InitTransaction()
+	defer func() {
+		err = u.transactionPkg.SettleTransaction(tx, err)
+	}()
+
+	err = u.tagRepo.LockTagHierarchy(tx)
+	if err != nil {
+		return err
+	}
+
+	mapVersionTags, err := u.tagRepo.GetPublishedVersionTagSerials(tx, serial)
+	if err != nil {
+		return err
+	}
+
+	err = u.tagRepo.DeleteVersionTags(tx, serial)
+	if err != nil {
+		return err
+	}
+
+	err = u.tagRepo.MoveTagChildren(tx, serial, tag.ParentSerial, serial)
+	if err != nil {
+		return err
+	}
+
+	err = u.tagRepo.DeleteTag(tx, serial)
+	if err != nil {
+		return err
+	}
+
+	for versionSerial, tagSerials := range mapVersionTags {
+		remainingTagSerials := []string{}
+		for _, tagSerial := range tagSerials {
+			if tagSerial != serial {
+				remainingTagSerials = append(remainingTagSerials, tagSerial)
+			}
+		}
+
+		err = u.scorer.updateTagRelationshipScore(tx, versionSerial, remainingTagSerials)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// merge the source tag into the target tag: versions, children and stats of the source move to the target,
+// then the source is deleted. Stats of the target are recounted from the published versions since
+// a version could have both tags, and the relationship scores of the published versions using the target are recomputed
+func (u *tagUsecase) MergeTag(req *entity.MergeTagRequest) (err error) {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	sourceTag, err := u.getTag(req.SourceSerial)
+	if err != nil {
+		return err
+	}
+	_, err = u.getTag(req.TargetSerial)
+	if err != nil {
+		return err
+	}
+
+	tx := u.transactionPkg.InitTransaction()
+	defer func() {
+		err = u.transactionPkg.SettleTransaction(tx, err)
+	}()
+
+	err = u.tagRepo.LockTagHierarchy(tx)
+	if err != nil {
+		return err
+	}
+
+	// target below the source is moved up first, so the children of the source can be moved under it without a cycle
+	descendantSerials, err := u.tagRepo.GetTagDescendantSerials(tx, req.SourceSerial)
+	if err != nil {
+		return err
+	}
+	for _, serial := range descendantSerials {
+		if serial == req.TargetSerial {
+			err = u.tagRepo.UpdateTagParent(tx, req.TargetSerial, sourceTag.ParentSerial)
+			if err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	err = u.tagRepo.MoveTagChildren(tx, req.SourceSerial, &req.TargetSerial, req.TargetSerial)
+	if err != nil {
+		return err
+	}
+
+	err = u.tagRepo.MergeVersionTags(tx, req.SourceSerial, req.TargetSerial)
+	if err != nil {
+		return err
+	}
+
+	// synonyms of the source and the source name itself become synonyms of the target
+	err = u.tagRepo.MoveTagSynonyms(tx, req.SourceSerial, req.TargetSerial)
+	if err != nil {
+		return err
+	}
+
+	err = u.tagRepo.DeleteTag(tx, req.SourceSerial)
+	if err != nil {
+		return err
+	}
+
+	err = u.tagRepo.InsertTagSynonym(tx, &entity.TagSynonym{
+		Name:      sourceTag.Name,
+		TagSerial: req.TargetSerial,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = u.tagRepo.RecountTagStats(tx, req.TargetSerial)
+	if err != nil {
+		return err
+	}
+
+	tagStats, err := u.tagRepo.GetTagStatsBySerials(tx, []string{req.TargetSerial})
+	if err != nil {
+		return err
+	}
+	err = u.scorer.updateTrendingScore(tx, tagStats)
+	if err != nil {
+		return err
+	}
+
+	mapVersionTags, err := u.tagRepo.GetPublishedVersionTagSerials(tx, req.TargetSerial)
+	if err != nil {
+		return err
+	}
+	for versionSerial, tagSerials := range mapVersionTags {
+		err = u.scorer.updateTagRelationshipScore(tx, versionSerial, tagSerials)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// build the co-occurrence graph of the top tags, edges are the tag pairs used together in published articles
+func (u *tagUsecase) GetTagGraph(req *entity.GetTagGraphRequest) (*entity.TagGraph, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	nodes, err := u.tagRepo.GetTagGraphNodes(req.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	graph := &entity.TagGraph{
+		Nodes: nodes,
+		Edges: []*entity.TagGraphEdge{},
+	}
+	if len(nodes) < 2 {
+		return graph, nil
+	}
+
+	tagSerials := []string{}
+	mapTagUsageCount := make(map[string]int)
+	for _, node := range nodes {
+		tagSerials = append(tagSerials, node.Serial)
+		mapTagUsageCount[node.Serial] = node.UsageCount
+	}
+
+	tagPairStats, err := u.tagRepo.GetTagPairStatsBySerials(nil, tagSerials)
+	if err != nil {
+		return nil, err
+	}
+
+	totalPublishedArticle, err := u.articleRepo.GetTotalPublishedArticle(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tps := range tagPairStats {
+		if tps.UsageCount < req.MinCooccurrence {
+			continue
+		}
+
+		graph.Edges = append(graph.Edges, &entity.TagGraphEdge{
+			Source: tps.Tag1Serial,
+			Target: tps.Tag2Serial,
+			Weight: tps.UsageCount,
+			PMI:    calculateTagRelationshipScore(mapTagUsageCount[tps.Tag1Serial], mapTagUsageCount[tps.Tag2Serial], tps.UsageCount, totalPublishedArticle),
+		})
+	}
+
+	return graph, nil
+}
+
+// rebuild the usage counts and pair stats of every tag from the published versions,
+// then recalculate the trending scores and the relationship scores of the published versions
+func (u *tagUsecase) RebuildTagStats() (err error) {
+	tx := u.transactionPkg.InitTransaction()
+	defer func() {
+		err = u.transactionPkg.SettleTransaction(tx, err)
+	}()
+
+	err = u.tagRepo.RebuildTagStats(tx)
+	if err != nil {
+		return err
+	}
+
+	pg := &entity.Pagination{}
+	pg.SetToDefault()
+	for {
+		var tagStats []*entity.TagStat
+		tagStats, err = u.tagRepo.GetTagStats(tx, pg)
+		if err != nil {
+			return err
+		}
+
+		err = u.scorer.updateTrendingScore(tx, tagStats)
+		if err != nil {
+			return err
+		}
+
+		pg.Page++
+		if pg.Page > pg.TotalPage {
+			break
+		}
+	}
+
+	mapVersionTags, err := u.tagRepo.GetPublishedVersionTagSerials(tx, "")
+	if err != nil {
+		return err
+	}
+	for versionSerial, tagSerials := range mapVersionTags {
+		err = u.scorer.updateTagRelationshipScore(tx, versionSerial, tagSerials)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// make sure the name is not used by another tag or synonym, names are case-insensitive
+func (u *tagUsecase) validateTagNameAvailable(name, tagSerial string) error {
+	mapValueSerial, err := u.tagRepo.GetTagSerialsByValues([]string{name})
+	if err != nil {
+		return err
+	}
+	if serial, ok := mapValueSerial[name]; ok && serial != tagSerial {
+		return errorutil.NewCustomError(errorutil.ErrConflict, fmt.Errorf("error tag name: name '%s' is used by tag '%s'", name, serial))
+	}
+
+	return nil
+}
+
+func (u *tagUsecase) CreateTagSynonym(req *entity.CreateTagSynonymRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	_, err := u.getTag(req.TagSerial)
+	if err != nil {
+		return err
+	}
+
+	err = u.validateTagNameAvailable(req.Name, "")
+	if err != nil {
+		return err
+	}
+
+	return u.tagRepo.InsertTagSynonym(nil, &entity.TagSynonym{
+		Name:      req.Name,
+		TagSerial: req.TagSerial,
+	})
+}
+
+func (u *tagUsecase) GetTagSynonyms(serial string) (*entity.GetTagSynonymsResponse, error) {
+	if serial == "" {
+		return nil, errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error get tag synonyms: serial is mandatory"))
+	}
+
+	_, err := u.getTag(serial)
+	if err != nil {
+		return nil, err
+	}
+
+	synonyms, err := u.tagRepo.GetTagSynonyms(serial)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.GetTagSynonymsResponse{
+		Synonyms: synonyms,
+	}, nil
+}
+
+func (u *tagUsecase) DeleteTagSynonym(serial, name string) error {
+	if serial == "" || name == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error delete tag synonym: serial and name are mandatory"))
+	}
+
+	return u.tagRepo.DeleteTagSynonym(serial, name)
+}
+
+func (u *tagUsecase) GetTrendingTags(req *entity.GetTrendingTagsRequest) (*entity.GetTrendingTagsResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	trendingTags, err := u.tagRepo.GetTrendingTags(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.GetTrendingTagsResponse{
+		Tags:       trendingTags,
+		Pagination: req.Pagination,
+	}, nil
+}
+
+// get trending score history of a tag, recorded on every worker run
+func (u *tagUsecase) GetTagTrend(req *entity.GetTagTrendRequest) (*entity.GetTagTrendResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	tag, err := u.getTag(req.TagSerial)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots, err := u.tagRepo.GetTagTrendingSnapshots(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.GetTagTrendResponse{
+		Tag:       tag,
+		From:      *req.From,
+		To:        *req.To,
+		Snapshots: snapshots,
+	}, nil
+}
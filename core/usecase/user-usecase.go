@@ -1,29 +1,58 @@
 package usecase
 
 import (
+	"article-versioning-api/config"
 	"article-versioning-api/core/entity"
 	"article-versioning-api/core/repository"
 	errorutil "article-versioning-api/utils/error"
+	serialutil "article-versioning-api/utils/serial"
+	transactionutil "article-versioning-api/utils/transaction"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type userUsecase struct {
-	userRepository repository.UserRepositoryInterface
-	authUsecase    AuthUsecaseInterface
+	userRepository  repository.UserRepositoryInterface
+	tokenRepository repository.TokenRepositoryInterface
+	authUsecase     AuthUsecaseInterface
+	transactionPkg  transactionutil.Transaction
+	cfg             *config.Config
 }
 
 type UserUsecaseInterface interface {
 	RegisterUser(req *entity.RegisterUserRequest) error
-	Login(req *entity.LoginRequest) (token string, err error)
+	CreateInvitation(req *entity.CreateInvitationRequest) (*entity.CreateInvitationResponse, error)
+	CreateFirstAdmin(req *entity.RegisterUserRequest) error
+	Login(req *entity.LoginRequest) (*entity.LoginResponse, error)
+	RefreshToken(req *entity.RefreshTokenRequest) (*entity.LoginResponse, error)
+	Logout(req *entity.LogoutRequest) error
+	ResetPassword(req *entity.ResetPasswordRequest) error
+	GetUsers(req *entity.GetUsersRequest) (*entity.GetUsersResponse, error)
+	UpdateUserRole(req *entity.UpdateUserRoleRequest) error
+	UpdateUserStatus(req *entity.UpdateUserStatusRequest) error
+	ForcePasswordReset(req *entity.ForcePasswordResetRequest) (*entity.ForcePasswordResetResponse, error)
+	GetUserAuditLogs(req *entity.GetUserAuditLogsRequest) (*entity.GetUserAuditLogsResponse, error)
+	PurgeExpiredTokens() error
 }
 
-func NewUserUsecase(userRepository repository.UserRepositoryInterface, authUsecase AuthUsecaseInterface) UserUsecaseInterface {
-	return &userUsecase{userRepository, authUsecase}
+func NewUserUsecase(userRepository repository.UserRepositoryInterface, tokenRepository repository.TokenRepositoryInterface, authUsecase AuthUsecaseInterface, transactionPkg transactionutil.Transaction, cfg *config.Config) UserUsecaseInterface {
+	return &userUsecase{userRepository, tokenRepository, authUsecase, transactionPkg, cfg}
 }
 
-func (u *userUsecase) RegisterUser(req *entity.RegisterUserRequest) error {
+const (
+	refreshTokenLength      = 64
+	temporaryPasswordLength = 16
+	invitationTokenLength   = 32
+)
+
+// public sign-up only creates readers, other roles need an invitation from an admin
+func (u *userUsecase) RegisterUser(req *entity.RegisterUserRequest) (err error) {
 	if err := req.Validate(); err != nil {
 		return err
 	}
@@ -34,26 +63,485 @@ func (u *userUsecase) RegisterUser(req *entity.RegisterUserRequest) error {
 	}
 	req.Hash = hash
 
-	return u.userRepository.CreateUser(req)
+	tx := u.transactionPkg.InitTransaction()
+	defer func() {
+		err = u.transactionPkg.SettleTransaction(tx, err)
+	}()
+
+	if req.InvitationToken == "" {
+		if !u.cfg.PublicRegistrationEnabled {
+			return errorutil.NewCustomError(errorutil.ErrForbidden, errors.New("error register user: public registration is disabled, an invitation is required"))
+		}
+		if req.Role != "" && req.Role != entity.UserRoleReader.String() {
+			return errorutil.NewCustomError(errorutil.ErrForbidden, fmt.Errorf("error register user: role '%s' requires an invitation", req.Role))
+		}
+		req.Role = entity.UserRoleReader.String()
+
+		return u.userRepository.CreateUser(tx, req)
+	}
+
+	invitationHash := hashToken(req.InvitationToken)
+	invitation, err := u.userRepository.GetInvitationByHashForUpdate(tx, invitationHash)
+	if err != nil {
+		return err
+	}
+	if invitation == nil || invitation.UsedAt != nil || time.Now().UTC().After(invitation.ExpiresAt) {
+		return errorutil.NewCustomError(errorutil.ErrForbidden, errors.New("error register user: invitation is invalid, used or expired"))
+	}
+	if invitation.Username != nil && *invitation.Username != req.Username {
+		return errorutil.NewCustomError(errorutil.ErrForbidden, errors.New("error register user: invitation is for another username"))
+	}
+	if req.Role != "" && req.Role != invitation.Role {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error register user: invitation is for role '%s'", invitation.Role))
+	}
+	req.Role = invitation.Role
+
+	err = u.userRepository.CreateUser(tx, req)
+	if err != nil {
+		return err
+	}
+
+	err = u.userRepository.UpdateInvitationUsed(tx, invitationHash, req.Username)
+	if err != nil {
+		return err
+	}
+
+	return u.userRepository.InsertUserAuditLog(tx, &entity.UserAuditLog{
+		TargetUsername: req.Username,
+		ActorUsername:  invitation.CreatedByUsername,
+		Action:         entity.UserAuditActionInvitationAccepted,
+		NewValue:       &invitation.Role,
+	})
+}
+
+// create a single use invitation to register with the role, the token is only returned here
+func (u *userUsecase) CreateInvitation(req *entity.CreateInvitationRequest) (*entity.CreateInvitationResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	token, err := serialutil.GenerateRandomId(invitationTokenLength)
+	if err != nil {
+		return nil, fmt.Errorf("error create invitation: error generate token: %s", err.Error())
+	}
+
+	invitation := &entity.Invitation{
+		TokenHash:         hashToken(token),
+		Role:              req.Role,
+		CreatedByUsername: req.ActorUsername,
+		ExpiresAt:         time.Now().Add(u.cfg.InvitationTTL).UTC(),
+	}
+	if req.Username != "" {
+		invitation.Username = &req.Username
+	}
+
+	err = u.userRepository.InsertInvitation(nil, invitation)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.CreateInvitationResponse{
+		Token:     token,
+		Role:      invitation.Role,
+		Username:  invitation.Username,
+		ExpiresAt: invitation.ExpiresAt,
+	}, nil
+}
+
+// create the first admin without invitation, used by the admin CLI to bootstrap a new installation
+func (u *userUsecase) CreateFirstAdmin(req *entity.RegisterUserRequest) (err error) {
+	req.Role = entity.UserRoleAdmin.String()
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	hash, err := generateHashPassword(req.Password)
+	if err != nil {
+		return fmt.Errorf("error create first admin: %s", err.Error())
+	}
+	req.Hash = hash
+
+	tx := u.transactionPkg.InitTransaction()
+	defer func() {
+		err = u.transactionPkg.SettleTransaction(tx, err)
+	}()
+
+	count, err := u.userRepository.CountUsersByRole(tx, entity.UserRoleAdmin.String())
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errorutil.NewCustomError(errorutil.ErrConflict, errors.New("error create first admin: an admin already exists, invite other admins"))
+	}
+
+	err = u.userRepository.CreateUser(tx, req)
+	if err != nil {
+		return err
+	}
+
+	return u.userRepository.InsertUserAuditLog(tx, &entity.UserAuditLog{
+		TargetUsername: req.Username,
+		ActorUsername:  req.Username,
+		Action:         entity.UserAuditActionAdminBootstrapped,
+		NewValue:       &req.Role,
+	})
 }
 
-func (u *userUsecase) Login(req *entity.LoginRequest) (token string, err error) {
+func (u *userUsecase) Login(req *entity.LoginRequest) (resp *entity.LoginResponse, err error) {
 	user, err := u.userRepository.GetUserByUsername(req.Username)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	err = validatePassword(user.Hash, req.Password)
 	if err != nil {
-		return "", errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error login: %s", err.Error()))
+		return nil, errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error login: %s", err.Error()))
+	}
+	if !user.IsActive {
+		return nil, errorutil.NewCustomError(errorutil.ErrForbidden, errors.New("error login: user is deactivated"))
+	}
+	if user.PasswordResetRequired {
+		return nil, errorutil.NewCustomError(errorutil.ErrForbidden, errors.New("error login: password reset is required, set a new password first"))
+	}
+
+	// every login starts a new refresh token family
+	familyID, err := serialutil.GenerateRandomId(tokenIDLength)
+	if err != nil {
+		return nil, fmt.Errorf("error login: error generate token family: %s", err.Error())
+	}
+
+	tx := u.transactionPkg.InitTransaction()
+	defer func() {
+		err = u.transactionPkg.SettleTransaction(tx, err)
+	}()
+
+	return u.issueTokens(tx, user, familyID)
+}
+
+// exchange a refresh token for a new access and refresh token, the refresh token can only be used once.
+// Using it again means it is leaked, so the whole family including its access tokens is revoked
+func (u *userUsecase) RefreshToken(req *entity.RefreshTokenRequest) (*entity.LoginResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	resp, reused, err := u.rotateRefreshToken(hashToken(req.RefreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return nil, errorutil.NewCustomError(errorutil.ErrUnauthorized, errors.New("error refresh token: refresh token is already used, all tokens of the login are revoked"))
+	}
+
+	return resp, nil
+}
+
+// the revocation on reuse is committed, so the reuse is returned instead of an error
+func (u *userUsecase) rotateRefreshToken(tokenHash string) (resp *entity.LoginResponse, reused bool, err error) {
+	tx := u.transactionPkg.InitTransaction()
+	defer func() {
+		err = u.transactionPkg.SettleTransaction(tx, err)
+	}()
+
+	refreshToken, err := u.tokenRepository.GetRefreshTokenByHashForUpdate(tx, tokenHash)
+	if err != nil {
+		return nil, false, err
+	}
+	if refreshToken == nil || refreshToken.RevokedAt != nil || time.Now().UTC().After(refreshToken.ExpiresAt) {
+		return nil, false, errorutil.NewCustomError(errorutil.ErrUnauthorized, errors.New("error refresh token: refresh token is invalid or expired"))
+	}
+	if refreshToken.UsedAt != nil {
+		err = u.tokenRepository.RevokeRefreshTokenFamily(tx, refreshToken.FamilyID, time.Now().Add(u.cfg.AccessTokenTTL))
+		return nil, err == nil, err
+	}
+
+	// role may have changed since the login
+	user, err := u.userRepository.GetUserByUsername(refreshToken.Username)
+	if err != nil {
+		return nil, false, err
+	}
+	if !user.IsActive || user.PasswordResetRequired {
+		return nil, false, errorutil.NewCustomError(errorutil.ErrUnauthorized, errors.New("error refresh token: user is deactivated or must reset password"))
+	}
+
+	err = u.tokenRepository.UpdateRefreshTokenUsed(tx, tokenHash)
+	if err != nil {
+		return nil, false, err
+	}
+
+	resp, err = u.issueTokens(tx, user, refreshToken.FamilyID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return resp, false, nil
+}
+
+// revoke the access token of the request and, when given, the refresh token family of the user
+func (u *userUsecase) Logout(req *entity.LogoutRequest) (err error) {
+	if req.TokenID == "" {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error logout: token id not found in context"))
+	}
+
+	tx := u.transactionPkg.InitTransaction()
+	defer func() {
+		err = u.transactionPkg.SettleTransaction(tx, err)
+	}()
+
+	err = u.tokenRepository.InsertRevokedToken(tx, req.TokenID, req.TokenExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	if req.RefreshToken == "" {
+		return nil
+	}
+
+	refreshToken, err := u.tokenRepository.GetRefreshTokenByHashForUpdate(tx, hashToken(req.RefreshToken))
+	if err != nil {
+		return err
+	}
+	if refreshToken == nil || refreshToken.Username != req.Username {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error logout: refresh token is invalid"))
+	}
+
+	return u.tokenRepository.RevokeRefreshTokenFamily(tx, refreshToken.FamilyID, time.Now().Add(u.cfg.AccessTokenTTL))
+}
+
+// set a new password with the current or temporary password, every token of the user is revoked
+func (u *userUsecase) ResetPassword(req *entity.ResetPasswordRequest) (err error) {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	tx := u.transactionPkg.InitTransaction()
+	defer func() {
+		err = u.transactionPkg.SettleTransaction(tx, err)
+	}()
+
+	user, err := u.userRepository.GetUserByUsernameForUpdate(tx, req.Username)
+	if err != nil {
+		return err
+	}
+	if user == nil || validatePassword(user.Hash, req.Password) != nil {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, errors.New("error reset password: username or password is wrong"))
+	}
+	if !user.IsActive {
+		return errorutil.NewCustomError(errorutil.ErrForbidden, errors.New("error reset password: user is deactivated"))
+	}
+
+	hash, err := generateHashPassword(req.NewPassword)
+	if err != nil {
+		return fmt.Errorf("error reset password: %s", err.Error())
+	}
+
+	err = u.userRepository.UpdateUserPassword(tx, user.Username, hash, false)
+	if err != nil {
+		return err
+	}
+
+	err = u.tokenRepository.RevokeUserTokens(tx, user.Username, time.Now().Add(u.cfg.AccessTokenTTL))
+	if err != nil {
+		return err
+	}
+
+	return u.userRepository.InsertUserAuditLog(tx, &entity.UserAuditLog{
+		TargetUsername: user.Username,
+		ActorUsername:  user.Username,
+		Action:         entity.UserAuditActionPasswordChanged,
+	})
+}
+
+func (u *userUsecase) GetUsers(req *entity.GetUsersRequest) (*entity.GetUsersResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	return u.userRepository.GetUsers(req)
+}
+
+func (u *userUsecase) UpdateUserRole(req *entity.UpdateUserRoleRequest) (err error) {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	tx := u.transactionPkg.InitTransaction()
+	defer func() {
+		err = u.transactionPkg.SettleTransaction(tx, err)
+	}()
+
+	user, err := u.getUserForUpdate(tx, req.Username)
+	if err != nil {
+		return err
+	}
+	if user.Role == req.Role {
+		return nil
+	}
+
+	// tokens are verified against the current role, so the change applies to tokens already issued
+	err = u.userRepository.UpdateUserRole(tx, user.Username, req.Role)
+	if err != nil {
+		return err
+	}
+
+	return u.userRepository.InsertUserAuditLog(tx, &entity.UserAuditLog{
+		TargetUsername: user.Username,
+		ActorUsername:  req.ActorUsername,
+		Action:         entity.UserAuditActionRoleChanged,
+		OldValue:       &user.Role,
+		NewValue:       &req.Role,
+	})
+}
+
+// deactivate or reactivate a user, every token of a deactivated user is revoked
+func (u *userUsecase) UpdateUserStatus(req *entity.UpdateUserStatusRequest) (err error) {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	tx := u.transactionPkg.InitTransaction()
+	defer func() {
+		err = u.transactionPkg.SettleTransaction(tx, err)
+	}()
+
+	user, err := u.getUserForUpdate(tx, req.Username)
+	if err != nil {
+		return err
+	}
+	if user.IsActive == *req.IsActive {
+		return nil
+	}
+
+	err = u.userRepository.UpdateUserActive(tx, user.Username, *req.IsActive)
+	if err != nil {
+		return err
+	}
+
+	action := entity.UserAuditActionReactivated
+	if !*req.IsActive {
+		action = entity.UserAuditActionDeactivated
+		err = u.tokenRepository.RevokeUserTokens(tx, user.Username, time.Now().Add(u.cfg.AccessTokenTTL))
+		if err != nil {
+			return err
+		}
+	}
+
+	return u.userRepository.InsertUserAuditLog(tx, &entity.UserAuditLog{
+		TargetUsername: user.Username,
+		ActorUsername:  req.ActorUsername,
+		Action:         action,
+	})
+}
+
+// replace the password of the user with a temporary password, which can only be used to set a new password.
+// Every token of the user is revoked
+func (u *userUsecase) ForcePasswordReset(req *entity.ForcePasswordResetRequest) (resp *entity.ForcePasswordResetResponse, err error) {
+	temporaryPassword, err := serialutil.GenerateRandomId(temporaryPasswordLength)
+	if err != nil {
+		return nil, fmt.Errorf("error force password reset: error generate temporary password: %s", err.Error())
+	}
+	hash, err := generateHashPassword(temporaryPassword)
+	if err != nil {
+		return nil, fmt.Errorf("error force password reset: %s", err.Error())
+	}
+
+	tx := u.transactionPkg.InitTransaction()
+	defer func() {
+		err = u.transactionPkg.SettleTransaction(tx, err)
+	}()
+
+	user, err := u.getUserForUpdate(tx, req.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.userRepository.UpdateUserPassword(tx, user.Username, hash, true)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.tokenRepository.RevokeUserTokens(tx, user.Username, time.Now().Add(u.cfg.AccessTokenTTL))
+	if err != nil {
+		return nil, err
 	}
 
-	token, err = u.authUsecase.CreateToken(user)
+	err = u.userRepository.InsertUserAuditLog(tx, &entity.UserAuditLog{
+		TargetUsername: user.Username,
+		ActorUsername:  req.ActorUsername,
+		Action:         entity.UserAuditActionPasswordResetForced,
+	})
 	if err != nil {
-		return token, err
+		return nil, err
 	}
 
-	return token, nil
+	return &entity.ForcePasswordResetResponse{TemporaryPassword: temporaryPassword}, nil
+}
+
+func (u *userUsecase) GetUserAuditLogs(req *entity.GetUserAuditLogsRequest) (*entity.GetUserAuditLogsResponse, error) {
+	if req.Pagination != nil {
+		req.Pagination.Validate()
+	}
+
+	return u.userRepository.GetUserAuditLogs(req)
+}
+
+// get and lock user by username, return bad request error if it is not found
+func (u *userUsecase) getUserForUpdate(tx *gorm.DB, username string) (*entity.User, error) {
+	user, err := u.userRepository.GetUserByUsernameForUpdate(tx, username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error get user: user '%s' is not found", username))
+	}
+
+	return user, nil
+}
+
+// create an access token and a refresh token of the family for the user
+func (u *userUsecase) issueTokens(tx *gorm.DB, user *entity.User, familyID string) (*entity.LoginResponse, error) {
+	accessToken, err := u.authUsecase.CreateToken(user)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := serialutil.GenerateRandomId(refreshTokenLength)
+	if err != nil {
+		return nil, fmt.Errorf("error issue tokens: error generate refresh token: %s", err.Error())
+	}
+
+	err = u.tokenRepository.InsertRefreshToken(tx, &entity.RefreshToken{
+		TokenHash:     hashToken(refreshToken),
+		FamilyID:      familyID,
+		Username:      user.Username,
+		AccessTokenID: accessToken.ID,
+		ExpiresAt:     time.Now().Add(u.cfg.RefreshTokenTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.LoginResponse{
+		Token:        accessToken.Token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(time.Until(accessToken.ExpiresAt).Seconds()),
+	}, nil
+}
+
+// delete the expired refresh tokens and revoked access tokens that triggered by worker
+func (u *userUsecase) PurgeExpiredTokens() (err error) {
+	tx := u.transactionPkg.InitTransaction()
+	defer func() {
+		err = u.transactionPkg.SettleTransaction(tx, err)
+	}()
+
+	return u.tokenRepository.DeleteExpiredTokens(tx)
+}
+
+// refresh tokens are random so a fast hash is enough, only the hash is stored
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 func generateHashPassword(password string) (string, error) {
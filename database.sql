@@ -3,12 +3,17 @@ CREATE TABLE users (
     username VARCHAR(50) NOT NULL,
     role VARCHAR(50) NOT NULL, -- reader, admin, writer, editor
     hash TEXT NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE, -- deactivated users can not login and their tokens are rejected
+    password_reset_required BOOLEAN NOT NULL DEFAULT FALSE, -- set by an admin, the user must set a new password before login
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP,
     UNIQUE(username)
 );
 
 CREATE TABLE articles (
     id SERIAL PRIMARY KEY,
     serial VARCHAR(25) NOT NULL,
+    owner_username VARCHAR(50) NOT NULL REFERENCES users(username), -- creator of the article
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP,
     deleted_at TIMESTAMP,
@@ -21,7 +26,7 @@ CREATE TABLE versions (
     author_username VARCHAR(50) NOT NULL REFERENCES users(username),
     version_number INT NOT NULL,
     article_serial VARCHAR(25) NOT NULL REFERENCES articles(serial),
-    status VARCHAR(50), -- draft, pubslished, archived
+    status VARCHAR(50), -- draft, in_review, changes_requested, approved, published, archived, deleted
     title TEXT NOT NULL,
     content TEXT NOT NULL,
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
@@ -29,21 +34,55 @@ CREATE TABLE versions (
     deleted_at TIMESTAMP,
     published_at TIMESTAMP,
     tag_relationship_score FLOAT DEFAULT 0,
+    restored_from_version_serial VARCHAR(25) REFERENCES versions(serial),
+    search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', title), 'A') || setweight(to_tsvector('english', content), 'B')
+    ) STORED, -- full-text search, title matches rank higher than content matches
     UNIQUE(serial),
-    UNIQUE(article_serial, serial)
+    UNIQUE(article_serial, serial),
+    UNIQUE(article_serial, version_number)
 );
 
 CREATE UNIQUE INDEX one_published_per_article ON versions(article_serial) WHERE status = 'published';
+CREATE INDEX versions_search_vector ON versions USING GIN(search_vector);
+
+CREATE TABLE version_reviews (
+    id SERIAL PRIMARY KEY,
+    version_serial VARCHAR(25) NOT NULL REFERENCES versions(serial),
+    from_status VARCHAR(50) NOT NULL,
+    to_status VARCHAR(50) NOT NULL, -- in_review, changes_requested, approved
+    reviewer_username VARCHAR(50) NOT NULL REFERENCES users(username),
+    note TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX version_reviews_version_serial ON version_reviews(version_serial);
+
+CREATE TABLE version_status_events (
+    id SERIAL PRIMARY KEY,
+    article_serial VARCHAR(25) NOT NULL REFERENCES articles(serial),
+    version_serial VARCHAR(25) NOT NULL REFERENCES versions(serial),
+    from_status VARCHAR(50) NOT NULL,
+    to_status VARCHAR(50) NOT NULL,
+    actor_username VARCHAR(50) REFERENCES users(username), -- null when changed by the system
+    reason VARCHAR(50) NOT NULL, -- status_updated, replaced_by_published, article_deleted, article_restored, scheduled
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX version_status_events_article_serial ON version_status_events(article_serial);
 
 CREATE TABLE tags (
     id SERIAL PRIMARY KEY,
     serial VARCHAR(25) NOT NULL,
     name TEXT NOT NULL,
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    parent_serial VARCHAR(25) REFERENCES tags(serial), -- NULL for root tag
     UNIQUE(serial),
     UNIQUE(name)
 );
 
+CREATE INDEX tags_parent_serial ON tags(parent_serial);
+
 CREATE TABLE version_tags (
     version_serial VARCHAR(25) NOT NULL REFERENCES versions(serial),
     tag_serial VARCHAR(25) NOT NULL REFERENCES tags(serial),
@@ -64,4 +103,115 @@ CREATE TABLE tag_pair_stats (
     usage_count INT DEFAULT 0,
     updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     PRIMARY KEY (tag1_serial, tag2_serial)
-);
\ No newline at end of file
+);
+
+CREATE TABLE tag_synonyms (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    tag_serial VARCHAR(25) NOT NULL REFERENCES tags(serial),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX tag_synonyms_lower_name ON tag_synonyms(LOWER(name));
+CREATE INDEX tag_synonyms_tag_serial ON tag_synonyms(tag_serial);
+
+CREATE TABLE tag_trending_snapshots (
+    id SERIAL PRIMARY KEY,
+    tag_serial VARCHAR(25) NOT NULL REFERENCES tags(serial),
+    usage_count INT NOT NULL,
+    trending_score FLOAT NOT NULL,
+    created_at TIMESTAMP NOT NULL -- UTC
+);
+
+CREATE INDEX tag_trending_snapshots_tag_created_at ON tag_trending_snapshots(tag_serial, created_at);
+CREATE INDEX tag_trending_snapshots_created_at ON tag_trending_snapshots(created_at);
+
+CREATE TABLE lexeme_stats (
+    lexeme TEXT PRIMARY KEY,
+    version_count INT NOT NULL -- published versions containing the lexeme, refreshed by the worker
+);
+
+CREATE TABLE tag_lexeme_stats (
+    tag_serial VARCHAR(25) NOT NULL REFERENCES tags(serial),
+    lexeme TEXT NOT NULL,
+    version_count INT NOT NULL, -- published versions with the tag containing the lexeme, refreshed by the worker
+    PRIMARY KEY (tag_serial, lexeme)
+);
+
+CREATE INDEX tag_lexeme_stats_lexeme ON tag_lexeme_stats(lexeme);
+
+CREATE TABLE article_coauthors (
+    article_serial VARCHAR(25) NOT NULL REFERENCES articles(serial),
+    username VARCHAR(50) NOT NULL REFERENCES users(username),
+    added_by_username VARCHAR(50) NOT NULL REFERENCES users(username),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (article_serial, username)
+);
+
+CREATE TABLE version_schedules (
+    id SERIAL PRIMARY KEY,
+    serial VARCHAR(25) NOT NULL,
+    article_serial VARCHAR(25) NOT NULL REFERENCES articles(serial),
+    version_serial VARCHAR(25) NOT NULL REFERENCES versions(serial),
+    action VARCHAR(50) NOT NULL, -- publish, unpublish
+    scheduled_at TIMESTAMP NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending', -- pending, done, failed, cancelled
+    created_by VARCHAR(50) NOT NULL REFERENCES users(username),
+    created_by_role VARCHAR(50) NOT NULL, -- role of the creator, the schedule is applied with it
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    executed_at TIMESTAMP,
+    error TEXT,
+    UNIQUE(serial)
+);
+
+CREATE INDEX pending_version_schedules ON version_schedules(scheduled_at) WHERE status = 'pending';
+
+CREATE TABLE refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    token_hash VARCHAR(64) NOT NULL, -- sha256 of the token, the token itself is not stored
+    family_id VARCHAR(25) NOT NULL, -- tokens rotated from the same login
+    username VARCHAR(50) NOT NULL REFERENCES users(username),
+    access_token_id VARCHAR(25) NOT NULL, -- jti of the access token issued with the refresh token
+    expires_at TIMESTAMP NOT NULL, -- UTC
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    used_at TIMESTAMP, -- rotated
+    revoked_at TIMESTAMP,
+    UNIQUE(token_hash)
+);
+
+CREATE INDEX refresh_tokens_family_id ON refresh_tokens(family_id);
+CREATE INDEX refresh_tokens_username ON refresh_tokens(username);
+CREATE INDEX refresh_tokens_expires_at ON refresh_tokens(expires_at);
+
+CREATE TABLE revoked_tokens (
+    token_id VARCHAR(25) PRIMARY KEY, -- jti of the revoked access token
+    expires_at TIMESTAMP NOT NULL, -- UTC, the row is not needed after the token expires
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX revoked_tokens_expires_at ON revoked_tokens(expires_at);
+
+CREATE TABLE user_audit_logs (
+    id SERIAL PRIMARY KEY,
+    target_username VARCHAR(50) NOT NULL REFERENCES users(username),
+    actor_username VARCHAR(50) NOT NULL REFERENCES users(username),
+    action VARCHAR(50) NOT NULL, -- role_changed, deactivated, reactivated, password_reset_forced, password_changed, invitation_accepted, admin_bootstrapped
+    old_value VARCHAR(50),
+    new_value VARCHAR(50),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX user_audit_logs_target_username ON user_audit_logs(target_username);
+
+CREATE TABLE user_invitations (
+    id SERIAL PRIMARY KEY,
+    token_hash VARCHAR(64) NOT NULL, -- sha256 of the token, the token itself is not stored
+    role VARCHAR(50) NOT NULL, -- role of the user registered with the invitation
+    username VARCHAR(50), -- only this username can use the invitation when set
+    created_by_username VARCHAR(50) NOT NULL REFERENCES users(username),
+    expires_at TIMESTAMP NOT NULL, -- UTC
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    used_at TIMESTAMP, -- UTC, an invitation can only be used once
+    used_by_username VARCHAR(50) REFERENCES users(username),
+    UNIQUE(token_hash)
+);
@@ -7,6 +7,7 @@ import (
 	generalutil "article-versioning-api/utils/general"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -71,6 +72,14 @@ func (h *articleHandler) GetArticleLatestDetail(c *gin.Context) {
 		return
 	}
 
+	// without published version only one version is found, it is the latest version
+	latestVersion := resp.LatestVersion
+	if latestVersion == nil {
+		latestVersion = resp.PublishedVersion
+	}
+	if latestVersion != nil {
+		c.Header("ETag", formatETag(latestVersion.Serial))
+	}
 	c.JSON(http.StatusOK, resp)
 }
 
@@ -85,6 +94,9 @@ func (h *articleHandler) CreateArticleVersion(c *gin.Context) {
 		})
 		return
 	}
+	if req.BaseVersionSerial == "" {
+		req.BaseVersionSerial = parseETag(c.GetHeader("If-Match"))
+	}
 
 	resp, err := h.articleUsecase.CreateArticleVersion(c, req)
 	if err != nil {
@@ -92,13 +104,95 @@ func (h *articleHandler) CreateArticleVersion(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", formatETag(resp.Version.Serial))
+	c.JSON(http.StatusCreated, resp)
+}
+
+func (h *articleHandler) UpdateVersionTags(c *gin.Context) {
+	articleSerial, _ := c.Params.Get("serial")
+	versionSerial, _ := c.Params.Get("versionSerial")
+
+	req := &entity.UpdateVersionTagsRequest{}
+	req.ArticleSerial = articleSerial
+	req.VersionSerial = versionSerial
+
+	if err := c.ShouldBind(req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			errorutil.Error: errorutil.CombineHTTPErrorMessage(http.StatusInternalServerError, err),
+		})
+		return
+	}
+
+	resp, err := h.articleUsecase.UpdateVersionTags(c, req)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *articleHandler) SuggestTags(c *gin.Context) {
+	req := &entity.SuggestTagsRequest{}
+	if err := c.ShouldBind(req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			errorutil.Error: errorutil.CombineHTTPErrorMessage(http.StatusInternalServerError, err),
+		})
+		return
+	}
+
+	resp, err := h.articleUsecase.SuggestTags(req)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *articleHandler) RestoreArticleVersion(c *gin.Context) {
+	articleSerial, _ := c.Params.Get("serial")
+	versionSerial, _ := c.Params.Get("versionSerial")
+
+	req := &entity.RestoreArticleVersionRequest{
+		ArticleSerial: articleSerial,
+		VersionSerial: versionSerial,
+	}
+
+	resp, err := h.articleUsecase.RestoreArticleVersion(c, req)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+func (h *articleHandler) MergeArticleVersions(c *gin.Context) {
+	articleSerial, _ := c.Params.Get("serial")
+	req := &entity.MergeArticleVersionsRequest{}
+
+	if err := c.ShouldBind(req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			errorutil.Error: errorutil.CombineHTTPErrorMessage(http.StatusInternalServerError, err),
+		})
+		return
+	}
+	req.ArticleSerial = articleSerial
+
+	resp, err := h.articleUsecase.MergeArticleVersions(c, req)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
 	c.JSON(http.StatusCreated, resp)
 }
 
 func (h *articleHandler) DeleteArticle(c *gin.Context) {
 	articleSerial, _ := c.Params.Get("serial")
 
-	err := h.articleUsecase.DeleteArticle(articleSerial)
+	err := h.articleUsecase.DeleteArticle(c, articleSerial)
 	if err != nil {
 		writeHTTPError(c, err)
 		return
@@ -109,6 +203,32 @@ func (h *articleHandler) DeleteArticle(c *gin.Context) {
 	})
 }
 
+func (h *articleHandler) RestoreDeletedArticle(c *gin.Context) {
+	articleSerial, _ := c.Params.Get("serial")
+
+	err := h.articleUsecase.RestoreDeletedArticle(c, articleSerial)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		message: fmt.Sprintf("success restore article '%s'", articleSerial),
+	})
+}
+
+func (h *articleHandler) PurgeDeletedArticles(c *gin.Context) {
+	err := h.articleUsecase.PurgeDeletedArticles()
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		message: "deleted articles past retention period are purged",
+	})
+}
+
 func (h *articleHandler) UpdateArticleVersionStatus(c *gin.Context) {
 	articleSerial, _ := c.Params.Get("serial")
 	versionSerial, _ := c.Params.Get("versionSerial")
@@ -124,7 +244,7 @@ func (h *articleHandler) UpdateArticleVersionStatus(c *gin.Context) {
 		return
 	}
 
-	err := h.articleUsecase.UpdateArticleVersionStatus(req)
+	err := h.articleUsecase.UpdateArticleVersionStatus(c, req)
 	if err != nil {
 		writeHTTPError(c, err)
 		return
@@ -135,6 +255,62 @@ func (h *articleHandler) UpdateArticleVersionStatus(c *gin.Context) {
 	})
 }
 
+func (h *articleHandler) GetVersionReviews(c *gin.Context) {
+	articleSerial, _ := c.Params.Get("serial")
+	versionSerial, _ := c.Params.Get("versionSerial")
+
+	resp, err := h.articleUsecase.GetVersionReviews(articleSerial, versionSerial)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *articleHandler) GetArticleTimeline(c *gin.Context) {
+	articleSerial, _ := c.Params.Get("serial")
+
+	req := &entity.GetArticleTimelineRequest{}
+	if err := c.ShouldBind(req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			errorutil.Error: errorutil.CombineHTTPErrorMessage(http.StatusInternalServerError, err),
+		})
+		return
+	}
+	req.ArticleSerial = articleSerial
+
+	resp, err := h.articleUsecase.GetArticleTimeline(req)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *articleHandler) GetRelatedArticles(c *gin.Context) {
+	articleSerial, _ := c.Params.Get("serial")
+
+	req := &entity.GetRelatedArticlesRequest{}
+	if err := c.ShouldBind(req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			errorutil.Error: errorutil.CombineHTTPErrorMessage(http.StatusInternalServerError, err),
+		})
+		return
+	}
+	req.ArticleSerial = articleSerial
+	req.Pagination = entity.ParseToPagination(req.Page, req.PageSize)
+
+	resp, err := h.articleUsecase.GetRelatedArticles(req)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
 func (h *articleHandler) GetVersionsByArticleSerial(c *gin.Context) {
 	articleSerial, _ := c.Params.Get("serial")
 
@@ -159,6 +335,31 @@ func (h *articleHandler) GetVersionBySerial(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
+func (h *articleHandler) GetVersionDiff(c *gin.Context) {
+	articleSerial, _ := c.Params.Get("serial")
+	fromVersionSerial, _ := c.Params.Get("versionSerial")
+	toVersionSerial, _ := c.Params.Get("targetVersionSerial")
+
+	req := &entity.GetVersionDiffRequest{}
+	if err := c.ShouldBind(req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			errorutil.Error: errorutil.CombineHTTPErrorMessage(http.StatusInternalServerError, err),
+		})
+		return
+	}
+	req.ArticleSerial = articleSerial
+	req.FromVersionSerial = fromVersionSerial
+	req.ToVersionSerial = toVersionSerial
+
+	resp, err := h.articleUsecase.GetVersionDiff(req)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
 func (h *articleHandler) UpdateTrendingScoreTags(c *gin.Context) {
 	pg := entity.Pagination{}
 	err := h.articleUsecase.UpdateTrendingScoreTags(&pg)
@@ -183,3 +384,155 @@ func (h *articleHandler) UpdateTrendingScoreTags(c *gin.Context) {
 		message: "tags trending score is updated",
 	})
 }
+
+func (h *articleHandler) UpdateTagRelationshipScores(c *gin.Context) {
+	err := h.articleUsecase.UpdateTagRelationshipScores()
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		message: "tag relationship score of published versions is updated",
+	})
+}
+
+func (h *articleHandler) UpdateLexemeStats(c *gin.Context) {
+	err := h.articleUsecase.UpdateLexemeStats()
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		message: "lexeme stats of published versions are updated",
+	})
+}
+
+func (h *articleHandler) ScheduleArticleVersion(c *gin.Context) {
+	articleSerial, _ := c.Params.Get("serial")
+	versionSerial, _ := c.Params.Get("versionSerial")
+
+	req := &entity.ScheduleArticleVersionRequest{}
+	if err := c.ShouldBind(req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			errorutil.Error: errorutil.CombineHTTPErrorMessage(http.StatusInternalServerError, err),
+		})
+		return
+	}
+	req.ArticleSerial = articleSerial
+	req.VersionSerial = versionSerial
+
+	resp, err := h.articleUsecase.ScheduleArticleVersion(c, req)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+func (h *articleHandler) GetVersionSchedules(c *gin.Context) {
+	articleSerial, _ := c.Params.Get("serial")
+
+	resp, err := h.articleUsecase.GetVersionSchedules(articleSerial)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *articleHandler) CancelVersionSchedule(c *gin.Context) {
+	articleSerial, _ := c.Params.Get("serial")
+	scheduleSerial, _ := c.Params.Get("scheduleSerial")
+
+	err := h.articleUsecase.CancelVersionSchedule(c, articleSerial, scheduleSerial)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		message: fmt.Sprintf("success cancel schedule '%s'", scheduleSerial),
+	})
+}
+
+func (h *articleHandler) GetArticleCoauthors(c *gin.Context) {
+	articleSerial, _ := c.Params.Get("serial")
+
+	resp, err := h.articleUsecase.GetArticleCoauthors(articleSerial)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *articleHandler) AddArticleCoauthor(c *gin.Context) {
+	req := &entity.AddArticleCoauthorRequest{}
+
+	if err := c.ShouldBind(req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			errorutil.Error: errorutil.CombineHTTPErrorMessage(http.StatusInternalServerError, err),
+		})
+		return
+	}
+	req.ArticleSerial, _ = c.Params.Get("serial")
+	req.ActorUsername = entity.GetContextUsername(c)
+	req.Role = entity.GetContextRole(c)
+
+	err := h.articleUsecase.AddArticleCoauthor(req)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		message: fmt.Sprintf("success add co-author '%s'", req.Username),
+	})
+}
+
+func (h *articleHandler) RemoveArticleCoauthor(c *gin.Context) {
+	req := &entity.RemoveArticleCoauthorRequest{
+		ActorUsername: entity.GetContextUsername(c),
+		Role:          entity.GetContextRole(c),
+	}
+	req.ArticleSerial, _ = c.Params.Get("serial")
+	req.Username, _ = c.Params.Get("username")
+
+	err := h.articleUsecase.RemoveArticleCoauthor(req)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		message: fmt.Sprintf("success remove co-author '%s'", req.Username),
+	})
+}
+
+func (h *articleHandler) ApplyDueVersionSchedules(c *gin.Context) {
+	err := h.articleUsecase.ApplyDueVersionSchedules()
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		message: "due version schedules are applied",
+	})
+}
+
+// version serial is used as the ETag of an article
+func formatETag(versionSerial string) string {
+	return fmt.Sprintf("%q", versionSerial)
+}
+
+func parseETag(etag string) string {
+	etag = strings.TrimSpace(etag)
+	etag = strings.TrimPrefix(etag, "W/")
+	return strings.Trim(etag, `"`)
+}
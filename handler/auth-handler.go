@@ -10,10 +10,16 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	workerSecretHeader = "X-Worker-Secret"
+)
+
 type AuthHandler interface {
 	VerifyToken(ctx *gin.Context)
 	VerifyNotMandatoryToken(ctx *gin.Context)
 	VerifyRole(authorizedRoles []string) gin.HandlerFunc
+	GetJWKS(ctx *gin.Context)
+	VerifyWorkerSecret(ctx *gin.Context)
 }
 
 type authHandler struct {
@@ -49,7 +55,7 @@ func (h *authHandler) VerifyNotMandatoryToken(ctx *gin.Context) {
 }
 
 func (h *authHandler) verifyAndSetContext(ctx *gin.Context, authToken []string) {
-	user, err := h.authUsecase.VerifyToken(authToken[0])
+	claims, err := h.authUsecase.VerifyToken(authToken[0])
 	if err != nil {
 		ctx.JSON(http.StatusUnauthorized, gin.H{
 			errorutil.Error: http.StatusText(http.StatusUnauthorized),
@@ -58,8 +64,10 @@ func (h *authHandler) verifyAndSetContext(ctx *gin.Context, authToken []string)
 		return
 	}
 
-	ctx.Set(entity.ContextUsername, user.Username)
-	ctx.Set(entity.ContextRole, user.Role)
+	ctx.Set(entity.ContextUsername, claims.Username)
+	ctx.Set(entity.ContextRole, claims.Role)
+	ctx.Set(entity.ContextTokenID, claims.ID)
+	ctx.Set(entity.ContextTokenExpiresAt, claims.ExpiresAt)
 
 	ctx.Next()
 }
@@ -81,3 +89,22 @@ func (h *authHandler) VerifyRole(authorizedRoles []string) gin.HandlerFunc {
 
 	}
 }
+
+// VerifyWorkerSecret only allows the worker, it sends WORKER_SECRET in the X-Worker-Secret header
+func (h *authHandler) VerifyWorkerSecret(ctx *gin.Context) {
+	err := h.authUsecase.VerifyWorkerSecret(ctx.GetHeader(workerSecretHeader))
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			errorutil.Error: http.StatusText(http.StatusUnauthorized),
+		})
+		ctx.Abort()
+		return
+	}
+
+	ctx.Next()
+}
+
+func (h *authHandler) GetJWKS(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, h.authUsecase.GetJWKS())
+}
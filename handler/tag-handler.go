@@ -4,6 +4,7 @@ import (
 	"article-versioning-api/core/entity"
 	"article-versioning-api/core/usecase"
 	errorutil "article-versioning-api/utils/error"
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -68,3 +69,215 @@ func (h *tagHandler) GetTagBySerial(c *gin.Context) {
 
 	c.JSON(http.StatusOK, resp)
 }
+
+func (h *tagHandler) GetTagChildren(c *gin.Context) {
+	serial, _ := c.Params.Get("serial")
+
+	resp, err := h.tagUsecase.GetTagChildren(serial)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *tagHandler) UpdateTagParent(c *gin.Context) {
+	serial, _ := c.Params.Get("serial")
+
+	req := &entity.UpdateTagParentRequest{}
+	if err := c.ShouldBind(req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			errorutil.Error: errorutil.CombineHTTPErrorMessage(http.StatusInternalServerError, err),
+		})
+		return
+	}
+	req.TagSerial = serial
+
+	err := h.tagUsecase.UpdateTagParent(req)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		message: fmt.Sprintf("success move tag '%s'", serial),
+	})
+}
+
+func (h *tagHandler) RenameTag(c *gin.Context) {
+	serial, _ := c.Params.Get("serial")
+
+	req := &entity.RenameTagRequest{}
+	if err := c.ShouldBind(req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			errorutil.Error: errorutil.CombineHTTPErrorMessage(http.StatusInternalServerError, err),
+		})
+		return
+	}
+	req.TagSerial = serial
+
+	err := h.tagUsecase.RenameTag(req)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		message: fmt.Sprintf("success rename tag '%s'", serial),
+	})
+}
+
+func (h *tagHandler) DeleteTag(c *gin.Context) {
+	serial, _ := c.Params.Get("serial")
+
+	err := h.tagUsecase.DeleteTag(serial)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		message: fmt.Sprintf("success delete tag '%s'", serial),
+	})
+}
+
+func (h *tagHandler) MergeTag(c *gin.Context) {
+	serial, _ := c.Params.Get("serial")
+
+	req := &entity.MergeTagRequest{}
+	if err := c.ShouldBind(req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			errorutil.Error: errorutil.CombineHTTPErrorMessage(http.StatusInternalServerError, err),
+		})
+		return
+	}
+	req.SourceSerial = serial
+
+	err := h.tagUsecase.MergeTag(req)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		message: fmt.Sprintf("success merge tag '%s' into '%s'", serial, req.TargetSerial),
+	})
+}
+
+func (h *tagHandler) CreateTagSynonym(c *gin.Context) {
+	serial, _ := c.Params.Get("serial")
+
+	req := &entity.CreateTagSynonymRequest{}
+	if err := c.ShouldBind(req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			errorutil.Error: errorutil.CombineHTTPErrorMessage(http.StatusInternalServerError, err),
+		})
+		return
+	}
+	req.TagSerial = serial
+
+	err := h.tagUsecase.CreateTagSynonym(req)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		message: fmt.Sprintf("success add synonym '%s' to tag '%s'", req.Name, serial),
+	})
+}
+
+func (h *tagHandler) GetTagSynonyms(c *gin.Context) {
+	serial, _ := c.Params.Get("serial")
+
+	resp, err := h.tagUsecase.GetTagSynonyms(serial)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *tagHandler) DeleteTagSynonym(c *gin.Context) {
+	serial, _ := c.Params.Get("serial")
+	name, _ := c.Params.Get("name")
+
+	err := h.tagUsecase.DeleteTagSynonym(serial, name)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		message: fmt.Sprintf("success delete synonym '%s' of tag '%s'", name, serial),
+	})
+}
+
+func (h *tagHandler) GetTagGraph(c *gin.Context) {
+	req := &entity.GetTagGraphRequest{}
+	if err := c.ShouldBind(req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			errorutil.Error: errorutil.CombineHTTPErrorMessage(http.StatusInternalServerError, err),
+		})
+		return
+	}
+
+	graph, err := h.tagUsecase.GetTagGraph(req)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	switch req.Format {
+	case entity.TagGraphFormatGraphML:
+		data, err := graph.GraphML()
+		if err != nil {
+			writeHTTPError(c, err)
+			return
+		}
+		c.Data(http.StatusOK, "application/graphml+xml; charset=utf-8", data)
+	case entity.TagGraphFormatDOT:
+		c.Data(http.StatusOK, "text/vnd.graphviz; charset=utf-8", graph.DOT())
+	default:
+		c.JSON(http.StatusOK, graph.JSONGraph())
+	}
+}
+
+func (h *tagHandler) GetTrendingTags(c *gin.Context) {
+	req := &entity.GetTrendingTagsRequest{}
+	if err := c.ShouldBind(req); err != nil {
+		writeHTTPError(c, errorutil.NewCustomError(errorutil.ErrBadRequest, err))
+		return
+	}
+
+	req.Pagination = entity.ParseToPagination(req.Page, req.PageSize)
+
+	resp, err := h.tagUsecase.GetTrendingTags(req)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *tagHandler) GetTagTrend(c *gin.Context) {
+	serial, _ := c.Params.Get("serial")
+
+	req := &entity.GetTagTrendRequest{}
+	if err := c.ShouldBind(req); err != nil {
+		writeHTTPError(c, errorutil.NewCustomError(errorutil.ErrBadRequest, err))
+		return
+	}
+	req.TagSerial = serial
+
+	resp, err := h.tagUsecase.GetTagTrend(req)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -40,6 +40,26 @@ func (h *userHandler) RegisterUser(c *gin.Context) {
 	})
 }
 
+func (h *userHandler) CreateInvitation(c *gin.Context) {
+	req := &entity.CreateInvitationRequest{}
+
+	if err := c.ShouldBind(req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			errorutil.Error: errorutil.CombineHTTPErrorMessage(http.StatusInternalServerError, err),
+		})
+		return
+	}
+	req.ActorUsername = entity.GetContextUsername(c)
+
+	resp, err := h.userUsecase.CreateInvitation(req)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
 func (h *userHandler) Login(c *gin.Context) {
 	req := &entity.LoginRequest{}
 
@@ -50,22 +70,218 @@ func (h *userHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := h.userUsecase.Login(req)
+	resp, err := h.userUsecase.Login(req)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *userHandler) RefreshToken(c *gin.Context) {
+	req := &entity.RefreshTokenRequest{}
+
+	if err := c.ShouldBind(req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			errorutil.Error: errorutil.CombineHTTPErrorMessage(http.StatusInternalServerError, err),
+		})
+		return
+	}
+
+	resp, err := h.userUsecase.RefreshToken(req)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *userHandler) Logout(c *gin.Context) {
+	req := &entity.LogoutRequest{}
+
+	// the refresh token is optional, so an empty body is allowed
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBind(req); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				errorutil.Error: errorutil.CombineHTTPErrorMessage(http.StatusInternalServerError, err),
+			})
+			return
+		}
+	}
+	req.Username = entity.GetContextUsername(c)
+	req.TokenID = entity.GetContextTokenID(c)
+	req.TokenExpiresAt = entity.GetContextTokenExpiresAt(c)
+
+	err := h.userUsecase.Logout(req)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		message: "logged out successfully",
+	})
+}
+
+func (h *userHandler) ResetPassword(c *gin.Context) {
+	req := &entity.ResetPasswordRequest{}
+
+	if err := c.ShouldBind(req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			errorutil.Error: errorutil.CombineHTTPErrorMessage(http.StatusInternalServerError, err),
+		})
+		return
+	}
+
+	err := h.userUsecase.ResetPassword(req)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		message: "password updated successfully",
+	})
+}
+
+func (h *userHandler) GetUsers(c *gin.Context) {
+	req := &entity.GetUsersRequest{}
+
+	if err := c.ShouldBind(req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			errorutil.Error: errorutil.CombineHTTPErrorMessage(http.StatusInternalServerError, err),
+		})
+		return
+	}
+
+	req.Pagination = entity.ParseToPagination(req.Page, req.PageSize)
+
+	resp, err := h.userUsecase.GetUsers(req)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *userHandler) UpdateUserRole(c *gin.Context) {
+	req := &entity.UpdateUserRoleRequest{}
+
+	if err := c.ShouldBind(req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			errorutil.Error: errorutil.CombineHTTPErrorMessage(http.StatusInternalServerError, err),
+		})
+		return
+	}
+	req.Username = c.Param("username")
+	req.ActorUsername = entity.GetContextUsername(c)
+
+	err := h.userUsecase.UpdateUserRole(req)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		message: "user role updated successfully",
+	})
+}
+
+func (h *userHandler) UpdateUserStatus(c *gin.Context) {
+	req := &entity.UpdateUserStatusRequest{}
+
+	if err := c.ShouldBind(req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			errorutil.Error: errorutil.CombineHTTPErrorMessage(http.StatusInternalServerError, err),
+		})
+		return
+	}
+	req.Username = c.Param("username")
+	req.ActorUsername = entity.GetContextUsername(c)
+
+	err := h.userUsecase.UpdateUserStatus(req)
 	if err != nil {
 		writeHTTPError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"token": token,
+		message: "user status updated successfully",
+	})
+}
+
+func (h *userHandler) ForcePasswordReset(c *gin.Context) {
+	req := &entity.ForcePasswordResetRequest{
+		Username:      c.Param("username"),
+		ActorUsername: entity.GetContextUsername(c),
+	}
+
+	resp, err := h.userUsecase.ForcePasswordReset(req)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *userHandler) GetUserAuditLogs(c *gin.Context) {
+	req := &entity.GetUserAuditLogsRequest{}
+
+	if err := c.ShouldBind(req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			errorutil.Error: errorutil.CombineHTTPErrorMessage(http.StatusInternalServerError, err),
+		})
+		return
+	}
+	req.Username = c.Param("username")
+	req.Pagination = entity.ParseToPagination(req.Page, req.PageSize)
+
+	resp, err := h.userUsecase.GetUserAuditLogs(req)
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *userHandler) PurgeExpiredTokens(c *gin.Context) {
+	err := h.userUsecase.PurgeExpiredTokens()
+	if err != nil {
+		writeHTTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		message: "expired tokens are deleted",
 	})
 }
 
 func writeHTTPError(c *gin.Context, err error) {
 	switch errorutil.GetErrorType(err) {
 	case errorutil.ErrBadRequest:
-		c.AbortWithStatusJSON(http.StatusBadRequest, generalutil.MapAny{
+		resp := generalutil.MapAny{
 			errorutil.Error: errorutil.CombineHTTPErrorMessage(http.StatusBadRequest, errorutil.GetOriginalError(err)),
+		}
+		if details := errorutil.GetErrorDetails(err); details != nil {
+			resp[errorutil.Details] = details
+		}
+		c.AbortWithStatusJSON(http.StatusBadRequest, resp)
+	case errorutil.ErrUnauthorized:
+		c.AbortWithStatusJSON(http.StatusUnauthorized, generalutil.MapAny{
+			errorutil.Error: errorutil.CombineHTTPErrorMessage(http.StatusUnauthorized, errorutil.GetOriginalError(err)),
+		})
+	case errorutil.ErrForbidden:
+		c.AbortWithStatusJSON(http.StatusForbidden, generalutil.MapAny{
+			errorutil.Error: errorutil.CombineHTTPErrorMessage(http.StatusForbidden, errorutil.GetOriginalError(err)),
+		})
+	case errorutil.ErrConflict:
+		c.AbortWithStatusJSON(http.StatusConflict, generalutil.MapAny{
+			errorutil.Error: errorutil.CombineHTTPErrorMessage(http.StatusConflict, errorutil.GetOriginalError(err)),
 		})
 	default:
 		c.AbortWithStatusJSON(http.StatusInternalServerError, generalutil.MapAny{
@@ -11,9 +11,21 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/lib/pq"
 	"gorm.io/gorm"
 )
 
+const (
+	versionNumberUniqueConstraint = "versions_article_serial_version_number_key"
+
+	// text search configuration of versions.search_vector
+	searchConfig          = "english"
+	searchHeadlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2"
+
+	// the content is HTML escaped before it is highlighted, so <mark> is the only markup of the snippet
+	htmlEscapedContent = `replace(replace(replace(replace(replace(v.content, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;')`
+)
+
 type articleRepository struct {
 	db     *sql.DB
 	cfg    *config.Config
@@ -29,9 +41,9 @@ func (r *articleRepository) GetDb() *sql.DB {
 }
 
 func (r *articleRepository) InsertArticleTx(tx *sql.Tx, article *entity.Article) error {
-	query := `INSERT INTO articles (serial) VALUES ($1)`
+	query := `INSERT INTO articles (serial, owner_username) VALUES ($1, $2)`
 
-	_, err := tx.Exec(query, article.Serial)
+	_, err := tx.Exec(query, article.Serial, article.OwnerUsername)
 	if err != nil {
 		return fmt.Errorf("error repo insert article: %v", err.Error())
 	}
@@ -40,10 +52,13 @@ func (r *articleRepository) InsertArticleTx(tx *sql.Tx, article *entity.Article)
 }
 
 func (r *articleRepository) InsertVersionTx(tx *sql.Tx, version *entity.Version) error {
-	query := `INSERT INTO versions (serial, author_username, version_number, article_serial, status, title, content) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	query := `INSERT INTO versions (serial, author_username, version_number, article_serial, status, title, content, restored_from_version_serial) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
 
-	_, err := tx.Exec(query, version.Serial, version.AuthorUsername, version.VersionNumber, version.ArticleSerial, version.Status, version.Title, version.Content)
+	_, err := tx.Exec(query, version.Serial, version.AuthorUsername, version.VersionNumber, version.ArticleSerial, version.Status, version.Title, version.Content, version.RestoredFromVersionSerial)
 	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == pq.ErrorCode(r.cfg.PSQLUniqueViolationErrorCode) && pqErr.Constraint == versionNumberUniqueConstraint {
+			return errorutil.NewCustomError(errorutil.ErrConflict, fmt.Errorf("error repo insert version: version number %d of article '%s' has been created by another request", version.VersionNumber, version.ArticleSerial))
+		}
 		return fmt.Errorf("error repo insert version: %v", err.Error())
 	}
 
@@ -78,6 +93,66 @@ func (r *articleRepository) InsertVersionTagsTx(tx *sql.Tx, versionSerial string
 	return nil
 }
 
+// replace all tags of the version with the given tags
+func (r *articleRepository) ReplaceVersionTags(tx *gorm.DB, versionSerial string, tagSerials []string) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	err := conn.Exec(`DELETE FROM version_tags WHERE version_serial = ?`, versionSerial).Error
+	if err != nil {
+		return fmt.Errorf("error repo replace version tags: %v", err.Error())
+	}
+
+	if len(tagSerials) == 0 {
+		return nil
+	}
+
+	versionTags := []*entity.VersionTag{}
+	for _, tagSerial := range tagSerials {
+		versionTags = append(versionTags, &entity.VersionTag{VersionSerial: versionSerial, TagSerial: tagSerial})
+	}
+
+	err = conn.Table("version_tags").Create(&versionTags).Error
+	if err != nil {
+		return fmt.Errorf("error repo replace version tags: %v", err.Error())
+	}
+
+	return nil
+}
+
+// get the status of the version and lock it until the transaction ends, so the status can not change meanwhile
+func (r *articleRepository) GetVersionStatusForUpdate(tx *gorm.DB, serial string) (string, error) {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	var status string
+	err := conn.Raw(`SELECT status FROM versions WHERE serial = ? FOR UPDATE`, serial).Scan(&status).Error
+	if err != nil {
+		return "", fmt.Errorf("error repo get version status for update: %v", err.Error())
+	}
+
+	return status, nil
+}
+
+func (r *articleRepository) GetVersionTagSerials(tx *gorm.DB, versionSerial string) ([]string, error) {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	tagSerials := []string{}
+	err := conn.Raw(`SELECT tag_serial FROM version_tags WHERE version_serial = ?`, versionSerial).Scan(&tagSerials).Error
+	if err != nil {
+		return nil, fmt.Errorf("error repo get version tag serials: %v", err.Error())
+	}
+
+	return tagSerials, nil
+}
+
 func (r *articleRepository) UpdateArticleVersionStatus(tx *gorm.DB, req *entity.UpdateArticleVersionStatusRequest) error {
 	conn := transactionutil.GetTransaction(tx)
 	if conn == nil {
@@ -103,6 +178,74 @@ func (r *articleRepository) UpdateArticleVersionStatus(tx *gorm.DB, req *entity.
 	return nil
 }
 
+func (r *articleRepository) InsertVersionReview(tx *gorm.DB, review *entity.VersionReview) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `INSERT INTO version_reviews (version_serial, from_status, to_status, reviewer_username, note) VALUES (?, ?, ?, ?, ?)`
+
+	err := conn.Exec(query, review.VersionSerial, review.FromStatus, review.ToStatus, review.ReviewerUsername, review.Note).Error
+	if err != nil {
+		return fmt.Errorf("error repo insert version review: %v", err.Error())
+	}
+
+	return nil
+}
+
+func (r *articleRepository) GetVersionReviews(versionSerial string) ([]*entity.VersionReview, error) {
+	reviews := []*entity.VersionReview{}
+
+	err := r.gormDB.Table("version_reviews").
+		Where("version_serial = ?", versionSerial).
+		Order("created_at ASC, id ASC").
+		Scan(&reviews).Error
+	if err != nil {
+		return nil, fmt.Errorf("error repo get version reviews: %s", err.Error())
+	}
+
+	return reviews, nil
+}
+
+func (r *articleRepository) InsertVersionStatusEvents(tx *gorm.DB, events []*entity.VersionStatusEvent) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `INSERT INTO version_status_events (article_serial, version_serial, from_status, to_status, actor_username, reason) VALUES (?, ?, ?, ?, ?, ?)`
+
+	for _, event := range events {
+		err := conn.Exec(query, event.ArticleSerial, event.VersionSerial, event.FromStatus, event.ToStatus, event.ActorUsername, event.Reason).Error
+		if err != nil {
+			return fmt.Errorf("error repo insert version status event: %v", err.Error())
+		}
+	}
+
+	return nil
+}
+
+func (r *articleRepository) GetVersionStatusEvents(req *entity.GetArticleTimelineRequest) ([]*entity.VersionStatusEvent, error) {
+	events := []*entity.VersionStatusEvent{}
+
+	db := r.gormDB.Table("version_status_events e").
+		Select("e.*, v.version_number").
+		Joins("INNER JOIN versions v ON v.serial = e.version_serial").
+		Where("e.article_serial = ?", req.ArticleSerial)
+
+	if req.PublicationOnly {
+		db = db.Where("e.from_status = ? OR e.to_status = ?", entity.VersionStatusPublished.String(), entity.VersionStatusPublished.String())
+	}
+
+	err := db.Order("e.created_at ASC, e.id ASC").Scan(&events).Error
+	if err != nil {
+		return nil, fmt.Errorf("error repo get version status events: %s", err.Error())
+	}
+
+	return events, nil
+}
+
 func (r *articleRepository) DeleteArticle(tx *gorm.DB, serial string) error {
 	conn := transactionutil.GetTransaction(tx)
 	if conn == nil {
@@ -135,6 +278,135 @@ func (r *articleRepository) DeleteVersionByArticleSerial(tx *gorm.DB, articleSer
 	return nil
 }
 
+func (r *articleRepository) GetArticleBySerial(serial string) (*entity.Article, error) {
+	articles := []*entity.Article{}
+
+	err := r.gormDB.Table("articles").
+		Select("serial, owner_username, created_at, updated_at, deleted_at").
+		Where("serial = ?", serial).
+		Scan(&articles).Error
+	if err != nil {
+		return nil, fmt.Errorf("error repo get article by serial: %s", err.Error())
+	}
+	if len(articles) == 0 {
+		return nil, nil
+	}
+
+	return articles[0], nil
+}
+
+func (r *articleRepository) RestoreArticle(tx *gorm.DB, serial string) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `UPDATE articles SET deleted_at = NULL, updated_at = NOW() WHERE serial = ?`
+
+	err := conn.Exec(query, serial).Error
+	if err != nil {
+		return fmt.Errorf("error repo restore article: %v", err.Error())
+	}
+
+	return nil
+}
+
+func (r *articleRepository) RestoreVersion(tx *gorm.DB, req *entity.UpdateArticleVersionStatusRequest) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	isPublished := req.NewStatus == entity.VersionStatusPublished.String()
+
+	query := `
+		UPDATE versions
+		SET
+			status = ?,
+			deleted_at = NULL,
+			updated_at = NOW(),
+			published_at = CASE WHEN ? THEN NOW() ELSE NULL END
+		WHERE serial = ? AND article_serial = ?
+	`
+
+	err := conn.Exec(query, req.NewStatus, isPublished, req.VersionSerial, req.ArticleSerial).Error
+	if err != nil {
+		return fmt.Errorf("error repo restore version: %v", err.Error())
+	}
+
+	return nil
+}
+
+// get status of every version right before the article was deleted, taken from the latest deleted event
+func (r *articleRepository) GetVersionStatusesBeforeDeleted(articleSerial string) (map[string]string, error) {
+	query := `
+		SELECT DISTINCT ON (version_serial) version_serial, from_status
+		FROM version_status_events
+		WHERE article_serial = ? AND reason = ?
+		ORDER BY version_serial, created_at DESC, id DESC
+	`
+
+	events := []*entity.VersionStatusEvent{}
+	err := r.gormDB.Raw(query, articleSerial, entity.VersionStatusEventReasonArticleDeleted).Scan(&events).Error
+	if err != nil {
+		return nil, fmt.Errorf("error repo get version statuses before deleted: %s", err.Error())
+	}
+
+	mapVersionStatus := make(map[string]string)
+	for _, event := range events {
+		mapVersionStatus[event.VersionSerial] = event.FromStatus
+	}
+
+	return mapVersionStatus, nil
+}
+
+// get articles that have been soft deleted longer than the retention days
+func (r *articleRepository) GetDeletedArticleSerials(retentionDays int, limit int) ([]string, error) {
+	serials := []string{}
+
+	err := r.gormDB.Table("articles").
+		Where("deleted_at IS NOT NULL AND deleted_at < NOW() - make_interval(days => ?)", retentionDays).
+		Order("deleted_at ASC").
+		Limit(limit).
+		Pluck("serial", &serials).Error
+	if err != nil {
+		return nil, fmt.Errorf("error repo get deleted article serials: %s", err.Error())
+	}
+
+	return serials, nil
+}
+
+// hard delete articles with their versions and all rows referring to them
+func (r *articleRepository) PurgeArticles(tx *gorm.DB, serials []string) error {
+	if len(serials) == 0 {
+		return nil
+	}
+
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	queries := []string{
+		`DELETE FROM version_tags WHERE version_serial IN (SELECT serial FROM versions WHERE article_serial IN ?)`,
+		`DELETE FROM version_reviews WHERE version_serial IN (SELECT serial FROM versions WHERE article_serial IN ?)`,
+		`DELETE FROM version_status_events WHERE article_serial IN ?`,
+		`DELETE FROM version_schedules WHERE article_serial IN ?`,
+		`DELETE FROM article_coauthors WHERE article_serial IN ?`,
+		`DELETE FROM versions WHERE article_serial IN ?`,
+		`DELETE FROM articles WHERE serial IN ?`,
+	}
+
+	for _, query := range queries {
+		err := conn.Exec(query, serials).Error
+		if err != nil {
+			return fmt.Errorf("error repo purge articles: %v", err.Error())
+		}
+	}
+
+	return nil
+}
+
 func (r *articleRepository) GetLatestVersionNumber(articleSerial string) (int, error) {
 	query := `SELECT MAX(v.version_number) AS latest_version_number 
 				FROM articles a 
@@ -170,10 +442,25 @@ func (r *articleRepository) GetArticles(req *entity.GetArticlesRequest) (*entity
 	if req.AuthorUsername != "" {
 		db = db.Where("v.author_username = ?", req.AuthorUsername)
 	}
-	if req.TagSerial != "" {
+	if req.TagSerial != "" && req.IncludeDescendants {
+		db = db.Where(`EXISTS (
+			SELECT 1 FROM version_tags vt
+			WHERE vt.version_serial = v.serial AND vt.tag_serial IN (
+				WITH RECURSIVE tag_tree AS (
+					SELECT serial FROM tags WHERE serial = ?
+					UNION
+					SELECT t.serial FROM tags t INNER JOIN tag_tree tt ON t.parent_serial = tt.serial
+				)
+				SELECT serial FROM tag_tree
+			)
+		)`, req.TagSerial)
+	} else if req.TagSerial != "" {
 		db = db.Joins("INNER JOIN version_tags vt ON vt.version_serial = v.serial").
 			Where("vt.tag_serial = ?", req.TagSerial)
 	}
+	if req.Query != "" {
+		db = db.Where("v.search_vector @@ websearch_to_tsquery(?::regconfig, ?)", searchConfig, req.Query)
+	}
 
 	var total int64
 	if req.Pagination != nil {
@@ -195,6 +482,12 @@ func (r *articleRepository) GetArticles(req *entity.GetArticlesRequest) (*entity
 		sortBy, sortType := sanitizeSort(req.SortBy, req.SortType)
 
 		db = db.Limit(int(limit)).Offset(int(offset)).Order(fmt.Sprint(sortBy, " ", sortType)).Select("v.*")
+		if req.Query != "" {
+			db = db.Select(`v.*,
+				ts_rank(v.search_vector, websearch_to_tsquery(?::regconfig, ?)) AS search_rank,
+				ts_headline(?::regconfig, `+htmlEscapedContent+`, websearch_to_tsquery(?::regconfig, ?), ?) AS snippet`,
+				searchConfig, req.Query, searchConfig, searchConfig, req.Query, searchHeadlineOptions)
+		}
 	}
 
 	if err := db.Scan(&dtoVersions).Error; err != nil {
@@ -271,6 +564,193 @@ func (r *articleRepository) GetVersionsByQuery(req *entity.GetVersionsByQueryReq
 	return versions, nil
 }
 
+func (r *articleRepository) GetVersionsBySerials(serials []string) ([]*entity.Version, error) {
+	dtoVersions := []*Version{}
+	if len(serials) == 0 {
+		return []*entity.Version{}, nil
+	}
+
+	err := r.gormDB.Table("versions").Where("serial IN ?", serials).Scan(&dtoVersions).Error
+	if err != nil {
+		return nil, fmt.Errorf("error repo get versions by serials: %s", err.Error())
+	}
+
+	versions, err := r.parseDTOToVersions(dtoVersions)
+	if err != nil {
+		return nil, fmt.Errorf("error repo get versions by serials: %s", err)
+	}
+
+	return versions, nil
+}
+
+// get tag serials of published versions (other than the excluded article) that use any of the tags
+// or any tag that has been paired with them, mapped by version serial
+func (r *articleRepository) GetRelatedPublishedVersionTags(tagSerials []string, excludeArticleSerial string) (map[string][]string, error) {
+	mapVersionTags := make(map[string][]string)
+	if len(tagSerials) == 0 {
+		return mapVersionTags, nil
+	}
+
+	query := `
+		SELECT vt.version_serial, vt.tag_serial
+		FROM version_tags vt
+		INNER JOIN versions v ON v.serial = vt.version_serial
+		INNER JOIN articles a ON a.serial = v.article_serial
+		WHERE v.status = ? AND a.deleted_at IS NULL AND v.article_serial <> ?
+		AND vt.version_serial IN (
+			SELECT version_serial FROM version_tags
+			WHERE tag_serial IN ?
+			OR tag_serial IN (SELECT tag2_serial FROM tag_pair_stats WHERE tag1_serial IN ? AND usage_count > 0)
+			OR tag_serial IN (SELECT tag1_serial FROM tag_pair_stats WHERE tag2_serial IN ? AND usage_count > 0)
+		)
+	`
+
+	dtoVersionTags := []*VersionTag{}
+	err := r.gormDB.Raw(query, entity.VersionStatusPublished.String(), excludeArticleSerial, tagSerials, tagSerials, tagSerials).
+		Scan(&dtoVersionTags).Error
+	if err != nil {
+		return nil, fmt.Errorf("error repo get related published version tags: %s", err.Error())
+	}
+
+	for _, vt := range dtoVersionTags {
+		mapVersionTags[vt.VersionSerial] = append(mapVersionTags[vt.VersionSerial], vt.TagSerial)
+	}
+
+	return mapVersionTags, nil
+}
+
+// get the lexemes of the text with their number of occurrences, using the same text search configuration as search_vector
+func (r *articleRepository) GetTextLexemes(text string) (map[string]int, error) {
+	mapLexeme := make(map[string]int)
+	if text == "" {
+		return mapLexeme, nil
+	}
+
+	query := `SELECT lexeme, COALESCE(array_length(positions, 1), 1) AS occurrence FROM unnest(to_tsvector(?::regconfig, ?))`
+
+	lexemes := []*struct {
+		Lexeme     string
+		Occurrence int
+	}{}
+	err := r.gormDB.Raw(query, searchConfig, text).Scan(&lexemes).Error
+	if err != nil {
+		return nil, fmt.Errorf("error repo get text lexemes: %s", err.Error())
+	}
+
+	for _, l := range lexemes {
+		mapLexeme[l.Lexeme] = l.Occurrence
+	}
+
+	return mapLexeme, nil
+}
+
+// get how many published versions contain each lexeme, overall and per tag,
+// the lexeme counts are read from the stats rebuilt by the worker since counting them scans every published version
+func (r *articleRepository) GetTagLexemeStats(lexemes []string) (*entity.TagLexemeStats, error) {
+	stats := &entity.TagLexemeStats{
+		LexemeVersionCount:    make(map[string]int),
+		TagVersionCount:       make(map[string]int),
+		TagLexemeVersionCount: make(map[string]map[string]int),
+	}
+
+	var totalVersion int64
+	err := r.gormDB.Table("versions").Where("status = ?", entity.VersionStatusPublished.String()).Count(&totalVersion).Error
+	if err != nil {
+		return nil, fmt.Errorf("error repo get tag lexeme stats: %s", err.Error())
+	}
+	stats.TotalVersion = int(totalVersion)
+	if len(lexemes) == 0 || totalVersion == 0 {
+		return stats, nil
+	}
+
+	lexemeCounts := []*struct {
+		Lexeme string
+		Total  int
+	}{}
+	query := `SELECT lexeme, version_count AS total FROM lexeme_stats WHERE lexeme IN ?`
+	err = r.gormDB.Raw(query, lexemes).Scan(&lexemeCounts).Error
+	if err != nil {
+		return nil, fmt.Errorf("error repo get tag lexeme stats: %s", err.Error())
+	}
+	for _, lc := range lexemeCounts {
+		stats.LexemeVersionCount[lc.Lexeme] = lc.Total
+	}
+
+	tagLexemeCounts := []*struct {
+		TagSerial string
+		Lexeme    string
+		Total     int
+	}{}
+	query = `SELECT tag_serial, lexeme, version_count AS total FROM tag_lexeme_stats WHERE lexeme IN ?`
+	err = r.gormDB.Raw(query, lexemes).Scan(&tagLexemeCounts).Error
+	if err != nil {
+		return nil, fmt.Errorf("error repo get tag lexeme stats: %s", err.Error())
+	}
+	tagSerials := []string{}
+	for _, tlc := range tagLexemeCounts {
+		if _, ok := stats.TagLexemeVersionCount[tlc.TagSerial]; !ok {
+			stats.TagLexemeVersionCount[tlc.TagSerial] = make(map[string]int)
+			tagSerials = append(tagSerials, tlc.TagSerial)
+		}
+		stats.TagLexemeVersionCount[tlc.TagSerial][tlc.Lexeme] = tlc.Total
+	}
+	if len(tagSerials) == 0 {
+		return stats, nil
+	}
+
+	tagCounts := []*struct {
+		TagSerial string
+		Total     int
+	}{}
+	err = r.gormDB.Table("version_tags vt").
+		Select("vt.tag_serial, COUNT(*) AS total").
+		Joins("INNER JOIN versions v ON v.serial = vt.version_serial").
+		Where("v.status = ? AND vt.tag_serial IN ?", entity.VersionStatusPublished.String(), tagSerials).
+		Group("vt.tag_serial").
+		Scan(&tagCounts).Error
+	if err != nil {
+		return nil, fmt.Errorf("error repo get tag lexeme stats: %s", err.Error())
+	}
+	for _, tc := range tagCounts {
+		stats.TagVersionCount[tc.TagSerial] = tc.Total
+	}
+
+	return stats, nil
+}
+
+// count the published versions containing each lexeme again, overall and per tag
+func (r *articleRepository) RebuildLexemeStats(tx *gorm.DB) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	queries := []string{
+		`DELETE FROM lexeme_stats`,
+		`INSERT INTO lexeme_stats (lexeme, version_count)
+		SELECT l.lexeme, COUNT(*)
+		FROM versions v, unnest(v.search_vector) l
+		WHERE v.status = @status
+		GROUP BY l.lexeme`,
+		`DELETE FROM tag_lexeme_stats`,
+		`INSERT INTO tag_lexeme_stats (tag_serial, lexeme, version_count)
+		SELECT vt.tag_serial, l.lexeme, COUNT(*)
+		FROM versions v
+		INNER JOIN version_tags vt ON vt.version_serial = v.serial,
+		unnest(v.search_vector) l
+		WHERE v.status = @status
+		GROUP BY vt.tag_serial, l.lexeme`,
+	}
+	for _, query := range queries {
+		err := conn.Exec(query, sql.Named("status", entity.VersionStatusPublished.String())).Error
+		if err != nil {
+			return fmt.Errorf("error repo rebuild lexeme stats: %s", err.Error())
+		}
+	}
+
+	return nil
+}
+
 func (r *articleRepository) GetVersionBySerial(serial string) (*entity.Version, error) {
 	dtoVersions := []*Version{}
 
@@ -298,6 +778,7 @@ func sanitizeSort(sortBy, sortType string) (string, string) {
 		"updated_at":             "a.updated_at",
 		"published_at":           "a.published_at",
 		"tag_relationship_score": "v.tag_relationship_score",
+		"relevance":              "search_rank",
 	}
 
 	var allowedSortType = map[string]string{
@@ -380,6 +861,71 @@ func (r *articleRepository) UpdateTagRelationshipScore(tx *gorm.DB, versionSeria
 	return nil
 }
 
+// get a page of published version serials ordered by serial after the given serial, with their tag serials
+func (r *articleRepository) GetPublishedVersionTagSerials(afterSerial string, limit int) ([]string, map[string][]string, error) {
+	versionSerials := []string{}
+	err := r.gormDB.Table("versions").
+		Where("status = ? AND serial > ?", entity.VersionStatusPublished.String(), afterSerial).
+		Order("serial").
+		Limit(limit).
+		Pluck("serial", &versionSerials).Error
+	if err != nil {
+		return nil, nil, fmt.Errorf("error repo get published version tag serials: %s", err.Error())
+	}
+
+	mapVersionTags := make(map[string][]string)
+	if len(versionSerials) == 0 {
+		return versionSerials, mapVersionTags, nil
+	}
+
+	versionTags := []*entity.VersionTag{}
+	err = r.gormDB.Table("version_tags").
+		Where("version_serial IN ?", versionSerials).
+		Order("version_serial, tag_serial").
+		Scan(&versionTags).Error
+	if err != nil {
+		return nil, nil, fmt.Errorf("error repo get published version tag serials: %s", err.Error())
+	}
+
+	for _, vt := range versionTags {
+		mapVersionTags[vt.VersionSerial] = append(mapVersionTags[vt.VersionSerial], vt.TagSerial)
+	}
+
+	return versionSerials, mapVersionTags, nil
+}
+
+// update the tag relationship score of many versions at once, versions whose score is unchanged are not touched
+func (r *articleRepository) UpdateTagRelationshipScores(tx *gorm.DB, mapVersionScore map[string]float32) error {
+	if len(mapVersionScore) == 0 {
+		return nil
+	}
+
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	serials := []string{}
+	scores := []float64{}
+	for serial, score := range mapVersionScore {
+		serials = append(serials, serial)
+		scores = append(scores, float64(score))
+	}
+
+	query := `
+		UPDATE versions v SET tag_relationship_score = s.score
+		FROM (SELECT UNNEST(?::TEXT[]) AS serial, UNNEST(?::FLOAT8[]) AS score) s
+		WHERE v.serial = s.serial AND v.tag_relationship_score IS DISTINCT FROM s.score
+	`
+
+	err := conn.Exec(query, pq.Array(serials), pq.Array(scores)).Error
+	if err != nil {
+		return fmt.Errorf("error repo update tag relationship scores: %v", err.Error())
+	}
+
+	return nil
+}
+
 func (r *articleRepository) GetTotalPublishedArticle(tx *gorm.DB) (int, error) {
 	conn := transactionutil.GetTransaction(tx)
 	if conn == nil {
@@ -397,3 +943,174 @@ func (r *articleRepository) GetTotalPublishedArticle(tx *gorm.DB) (int, error) {
 
 	return int(total), nil
 }
+
+func (r *articleRepository) InsertVersionSchedules(tx *gorm.DB, schedules []*entity.VersionSchedule) error {
+	if len(schedules) == 0 {
+		return nil
+	}
+
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `INSERT INTO version_schedules (serial, article_serial, version_serial, action, scheduled_at, status, created_by, created_by_role) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	for _, schedule := range schedules {
+		err := conn.Exec(query, schedule.Serial, schedule.ArticleSerial, schedule.VersionSerial, schedule.Action, schedule.ScheduledAt.UTC(), schedule.Status, schedule.CreatedBy, schedule.CreatedByRole).Error
+		if err != nil {
+			return fmt.Errorf("error repo insert version schedule: %v", err.Error())
+		}
+	}
+
+	return nil
+}
+
+func (r *articleRepository) GetVersionSchedulesByArticleSerial(articleSerial string) ([]*entity.VersionSchedule, error) {
+	schedules := []*entity.VersionSchedule{}
+
+	err := r.gormDB.Table("version_schedules").
+		Where("article_serial = ?", articleSerial).
+		Order("scheduled_at ASC").
+		Scan(&schedules).Error
+	if err != nil {
+		return nil, fmt.Errorf("error repo get version schedules by article serial: %s", err.Error())
+	}
+
+	return schedules, nil
+}
+
+// get pending schedules that are due, the earliest first
+func (r *articleRepository) GetDueVersionSchedules(limit int) ([]*entity.VersionSchedule, error) {
+	schedules := []*entity.VersionSchedule{}
+
+	err := r.gormDB.Table("version_schedules").
+		Where("status = ? AND scheduled_at <= (NOW() AT TIME ZONE 'UTC')", entity.ScheduleStatusPending).
+		Order("scheduled_at ASC").
+		Limit(limit).
+		Scan(&schedules).Error
+	if err != nil {
+		return nil, fmt.Errorf("error repo get due version schedules: %s", err.Error())
+	}
+
+	return schedules, nil
+}
+
+func (r *articleRepository) UpdateVersionScheduleStatus(tx *gorm.DB, serial, status string, errorMessage *string) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `UPDATE version_schedules SET status = ?, error = ?, executed_at = NOW() WHERE serial = ?`
+
+	err := conn.Exec(query, status, errorMessage, serial).Error
+	if err != nil {
+		return fmt.Errorf("error repo update version schedule status: %v", err.Error())
+	}
+
+	return nil
+}
+
+func (r *articleRepository) CancelVersionSchedule(tx *gorm.DB, articleSerial, serial string) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `UPDATE version_schedules SET status = ? WHERE serial = ? AND article_serial = ? AND status = ?`
+
+	result := conn.Exec(query, entity.ScheduleStatusCancelled, serial, articleSerial, entity.ScheduleStatusPending)
+	if result.Error != nil {
+		return fmt.Errorf("error repo cancel version schedule: %v", result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error repo cancel version schedule: pending schedule '%s' is not found", serial))
+	}
+
+	return nil
+}
+
+func (r *articleRepository) CancelVersionSchedulesByArticleSerial(tx *gorm.DB, articleSerial string) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `UPDATE version_schedules SET status = ? WHERE article_serial = ? AND status = ?`
+
+	err := conn.Exec(query, entity.ScheduleStatusCancelled, articleSerial, entity.ScheduleStatusPending).Error
+	if err != nil {
+		return fmt.Errorf("error repo cancel version schedules by article serial: %v", err.Error())
+	}
+
+	return nil
+}
+
+func (r *articleRepository) GetArticleCoauthors(articleSerial string) ([]*entity.ArticleCoauthor, error) {
+	coauthors := []*entity.ArticleCoauthor{}
+
+	err := r.gormDB.Table("article_coauthors").
+		Select("username, added_by_username, created_at").
+		Where("article_serial = ?", articleSerial).
+		Order("created_at ASC, username ASC").
+		Scan(&coauthors).Error
+	if err != nil {
+		return nil, fmt.Errorf("error repo get article coauthors: %s", err.Error())
+	}
+
+	return coauthors, nil
+}
+
+func (r *articleRepository) IsArticleCoauthor(articleSerial, username string) (bool, error) {
+	var count int64
+	err := r.gormDB.Table("article_coauthors").
+		Where("article_serial = ? AND username = ?", articleSerial, username).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("error repo check article coauthor: %s", err.Error())
+	}
+
+	return count > 0, nil
+}
+
+// insert the co-author, adding an existing co-author again is ignored
+func (r *articleRepository) InsertArticleCoauthor(tx *gorm.DB, articleSerial string, coauthor *entity.ArticleCoauthor) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `INSERT INTO article_coauthors (article_serial, username, added_by_username) VALUES (?, ?, ?)
+		ON CONFLICT (article_serial, username) DO NOTHING`
+
+	err := conn.Exec(query, articleSerial, coauthor.Username, coauthor.AddedByUsername).Error
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == pq.ErrorCode(r.cfg.PSQLForeignKeyErrorCode) {
+			return errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error repo insert article coauthor: user '%s' is not found", coauthor.Username))
+		}
+		return fmt.Errorf("error repo insert article coauthor: %v", err.Error())
+	}
+
+	return nil
+}
+
+func (r *articleRepository) DeleteArticleCoauthor(tx *gorm.DB, articleSerial, username string) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `DELETE FROM article_coauthors WHERE article_serial = ? AND username = ?`
+
+	result := conn.Exec(query, articleSerial, username)
+	if result.Error != nil {
+		return fmt.Errorf("error repo delete article coauthor: %v", result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error repo delete article coauthor: '%s' is not a co-author", username))
+	}
+
+	return nil
+}
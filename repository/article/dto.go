@@ -6,34 +6,40 @@ import (
 )
 
 type Version struct {
-	Serial               string     `json:"serial"`
-	AuthorUsername       string     `json:"authorUsername"`
-	VersionNumber        int        `json:"versionNumber"`
-	ArticleSerial        string     `json:"articleSerial"`
-	Title                string     `json:"title"`
-	Content              string     `json:"content"`
-	Status               string     `json:"status"`
-	CreatedAt            time.Time  `json:"createdAt"`
-	UpdatedAt            *time.Time `json:"updatedAt"`
-	DeletedAt            *time.Time `json:"deletedAt"`
-	PublishedAt          *time.Time `json:"publishedAt"`
-	TagRelationshipScore float32    `json:"tagRelationshipScore"`
+	Serial                    string     `json:"serial"`
+	AuthorUsername            string     `json:"authorUsername"`
+	VersionNumber             int        `json:"versionNumber"`
+	ArticleSerial             string     `json:"articleSerial"`
+	Title                     string     `json:"title"`
+	Content                   string     `json:"content"`
+	Status                    string     `json:"status"`
+	CreatedAt                 time.Time  `json:"createdAt"`
+	UpdatedAt                 *time.Time `json:"updatedAt"`
+	DeletedAt                 *time.Time `json:"deletedAt"`
+	PublishedAt               *time.Time `json:"publishedAt"`
+	TagRelationshipScore      float32    `json:"tagRelationshipScore"`
+	RestoredFromVersionSerial *string    `json:"restoredFromVersionSerial"`
+	SearchRank                float32    `json:"searchRank"`
+	Snippet                   string     `json:"snippet"`
 }
 
 func (v *Version) parseToVersion() *entity.Version {
 	return &entity.Version{
-		Serial:               v.Serial,
-		AuthorUsername:       v.AuthorUsername,
-		VersionNumber:        v.VersionNumber,
-		ArticleSerial:        v.ArticleSerial,
-		Title:                v.Title,
-		Content:              v.Content,
-		Status:               v.Status,
-		CreatedAt:            v.CreatedAt,
-		UpdatedAt:            v.UpdatedAt,
-		DeletedAt:            v.DeletedAt,
-		PublishedAt:          v.PublishedAt,
-		TagRelationshipScore: v.TagRelationshipScore,
+		Serial:                    v.Serial,
+		AuthorUsername:            v.AuthorUsername,
+		VersionNumber:             v.VersionNumber,
+		ArticleSerial:             v.ArticleSerial,
+		Title:                     v.Title,
+		Content:                   v.Content,
+		Status:                    v.Status,
+		CreatedAt:                 v.CreatedAt,
+		UpdatedAt:                 v.UpdatedAt,
+		DeletedAt:                 v.DeletedAt,
+		PublishedAt:               v.PublishedAt,
+		TagRelationshipScore:      v.TagRelationshipScore,
+		RestoredFromVersionSerial: v.RestoredFromVersionSerial,
+		SearchRank:                v.SearchRank,
+		Snippet:                   v.Snippet,
 	}
 }
 
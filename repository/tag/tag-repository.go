@@ -1,22 +1,28 @@
 package tagrepository
 
 import (
+	"article-versioning-api/config"
 	"article-versioning-api/core/entity"
 	"article-versioning-api/core/repository"
+	errorutil "article-versioning-api/utils/error"
 	transactionutil "article-versioning-api/utils/transaction"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 
+	"github.com/lib/pq"
 	"gorm.io/gorm"
 )
 
 type tagRepository struct {
 	db     *sql.DB
+	cfg    *config.Config
 	gormDB *gorm.DB
 }
 
-func NewTagRepository(db *sql.DB, gormDB *gorm.DB) repository.TagRepositoryInterface {
-	return &tagRepository{db, gormDB}
+func NewTagRepository(db *sql.DB, cfg *config.Config, gormDB *gorm.DB) repository.TagRepositoryInterface {
+	return &tagRepository{db, cfg, gormDB}
 }
 
 func (r *tagRepository) InsertTag(tag *entity.Tag, tx *gorm.DB) error {
@@ -25,9 +31,9 @@ func (r *tagRepository) InsertTag(tag *entity.Tag, tx *gorm.DB) error {
 		conn = r.gormDB
 	}
 
-	query := `INSERT INTO tags (serial, name) VALUES (?, ?)`
+	query := `INSERT INTO tags (serial, name, parent_serial) VALUES (?, ?, ?)`
 
-	err := conn.Exec(query, tag.Serial, tag.Name).Error
+	err := conn.Exec(query, tag.Serial, tag.Name, tag.ParentSerial).Error
 	if err != nil {
 		return fmt.Errorf("error repo insert tag: %v", err.Error())
 	}
@@ -35,6 +41,78 @@ func (r *tagRepository) InsertTag(tag *entity.Tag, tx *gorm.DB) error {
 	return nil
 }
 
+func (r *tagRepository) GetTagChildren(serial string) ([]*entity.TagDetail, error) {
+	tagDetails := []*entity.TagDetail{}
+
+	err := r.gormDB.Table("tags t").
+		Select("t.serial, t.name, t.parent_serial, ts.usage_count, ts.trending_score").
+		Joins("LEFT JOIN tag_stats ts ON ts.tag_serial = t.serial").
+		Where("t.parent_serial = ?", serial).
+		Order("t.name ASC").
+		Scan(&tagDetails).Error
+	if err != nil {
+		return nil, fmt.Errorf("error repo get tag children: %s", err.Error())
+	}
+
+	return tagDetails, nil
+}
+
+// get serials of all tags below the tag in the hierarchy, excluding the tag itself
+func (r *tagRepository) GetTagDescendantSerials(tx *gorm.DB, serial string) ([]string, error) {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `
+		WITH RECURSIVE tag_tree AS (
+			SELECT serial FROM tags WHERE parent_serial = ?
+			UNION
+			SELECT t.serial FROM tags t INNER JOIN tag_tree tt ON t.parent_serial = tt.serial
+		)
+		SELECT serial FROM tag_tree
+	`
+
+	serials := []string{}
+	err := conn.Raw(query, serial).Scan(&serials).Error
+	if err != nil {
+		return nil, fmt.Errorf("error repo get tag descendant serials: %s", err.Error())
+	}
+
+	return serials, nil
+}
+
+// serialize changes of the tag hierarchy until the transaction ends, so concurrent moves can not create a cycle
+func (r *tagRepository) LockTagHierarchy(tx *gorm.DB) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	err := conn.Exec(`SELECT pg_advisory_xact_lock(hashtext('tag_hierarchy'))`).Error
+	if err != nil {
+		return fmt.Errorf("error repo lock tag hierarchy: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (r *tagRepository) UpdateTagParent(tx *gorm.DB, serial string, parentSerial *string) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `UPDATE tags SET parent_serial = ? WHERE serial = ?`
+
+	err := conn.Exec(query, parentSerial, serial).Error
+	if err != nil {
+		return fmt.Errorf("error repo update tag parent: %v", err.Error())
+	}
+
+	return nil
+}
+
 func (r *tagRepository) InsertTagStat(tagSerial string, tx *gorm.DB) error {
 	conn := transactionutil.GetTransaction(tx)
 	if conn == nil {
@@ -71,7 +149,7 @@ func (r *tagRepository) GetTags(pagination *entity.Pagination) ([]*entity.TagDet
 		limit := pagination.PageSize
 		offset := pagination.GetOffset()
 
-		db = db.Select("t.serial, t.name, ts.usage_count, ts.trending_score").Limit(int(limit)).Offset(int(offset)).Order("created_at DESC")
+		db = db.Select("t.serial, t.name, t.parent_serial, ts.usage_count, ts.trending_score").Limit(int(limit)).Offset(int(offset)).Order("created_at DESC")
 	}
 
 	if err := db.Scan(&tagDetails).Error; err != nil {
@@ -85,7 +163,7 @@ func (r *tagRepository) GetTagBySerial(serial string) (*entity.TagDetail, error)
 	tagDetail := &entity.TagDetail{}
 
 	err := r.gormDB.Table("tags t").
-		Select("t.serial, t.name, ts.usage_count, ts.trending_score").
+		Select("t.serial, t.name, t.parent_serial, ts.usage_count, ts.trending_score").
 		Where("t.serial = ?", serial).
 		Joins("LEFT JOIN tag_stats ts ON ts.tag_serial = t.serial").Scan(&tagDetail).Error
 	if err != nil {
@@ -190,7 +268,7 @@ func (r *tagRepository) DecrementTagPairStat(tx *gorm.DB, tag1Serial, tag2Serial
 	}
 
 	query := `UPDATE tag_pair_stats 
-		SET usage_count = tag_pair_stats.usage_count-1, updated_at = NOW()
+		SET usage_count = GREATEST(tag_pair_stats.usage_count-1, 0), updated_at = NOW()
 		WHERE tag1_serial = ? AND tag2_serial = ?`
 
 	err := conn.Exec(query, tag1Serial, tag2Serial).Error
@@ -219,9 +297,14 @@ func (r *tagRepository) GetTagPairStatsBySerials(tx *gorm.DB, serials []string)
 }
 
 func (r *tagRepository) GetTagStats(tx *gorm.DB, pg *entity.Pagination) ([]*entity.TagStat, error) {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
 	tagStats := []*entity.TagStat{}
 
-	db := r.gormDB.Table("tag_stats")
+	db := conn.Table("tag_stats")
 
 	var total int64
 	if pg != nil {
@@ -246,3 +329,499 @@ func (r *tagRepository) GetTagStats(tx *gorm.DB, pg *entity.Pagination) ([]*enti
 
 	return tagStats, nil
 }
+
+// record the current stats of all tags, the timestamp is in UTC
+func (r *tagRepository) InsertTagTrendingSnapshots(tx *gorm.DB) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `
+		INSERT INTO tag_trending_snapshots (tag_serial, usage_count, trending_score, created_at)
+		SELECT tag_serial, usage_count, trending_score, (NOW() AT TIME ZONE 'UTC') FROM tag_stats
+	`
+
+	err := conn.Exec(query).Error
+	if err != nil {
+		return fmt.Errorf("error repo insert tag trending snapshots: %s", err.Error())
+	}
+
+	return nil
+}
+
+// delete the snapshots that are older than the retention period
+func (r *tagRepository) DeleteExpiredTagTrendingSnapshots(tx *gorm.DB, retentionDays int) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `DELETE FROM tag_trending_snapshots WHERE created_at < (NOW() AT TIME ZONE 'UTC') - make_interval(days => ?)`
+
+	err := conn.Exec(query, retentionDays).Error
+	if err != nil {
+		return fmt.Errorf("error repo delete tag trending snapshots: %s", err.Error())
+	}
+
+	return nil
+}
+
+// get tags ordered by current trending score, or by average trending score of the snapshots within the window
+func (r *tagRepository) GetTrendingTags(req *entity.GetTrendingTagsRequest) ([]*entity.TrendingTag, error) {
+	trendingTags := []*entity.TrendingTag{}
+
+	var db *gorm.DB
+	if req.WindowDuration > 0 {
+		db = r.gormDB.Table("tag_trending_snapshots s").
+			Joins("INNER JOIN tags t ON t.serial = s.tag_serial").
+			Joins("INNER JOIN tag_stats ts ON ts.tag_serial = t.serial").
+			Where("s.created_at >= (NOW() AT TIME ZONE 'UTC') - make_interval(secs => ?)", req.WindowDuration.Seconds())
+	} else {
+		db = r.gormDB.Table("tags t").
+			Joins("INNER JOIN tag_stats ts ON ts.tag_serial = t.serial")
+	}
+
+	if req.Pagination != nil {
+		var total int64
+		// count on a copy so the distinct does not leak into the select below
+		if err := db.Session(&gorm.Session{}).Distinct("t.serial").Count(&total).Error; err != nil {
+			return nil, fmt.Errorf("error repo get trending tags: %s", err.Error())
+		}
+		if total == 0 {
+			return trendingTags, nil
+		}
+		req.Pagination.Total = int(total)
+
+		req.Pagination.SetPagination()
+		db = db.Limit(req.Pagination.PageSize).Offset(req.Pagination.GetOffset())
+	}
+
+	if req.WindowDuration > 0 {
+		db = db.Select(`t.serial, t.name, ts.usage_count,
+			AVG(s.trending_score) AS trending_score,
+			(ARRAY_AGG(s.trending_score ORDER BY s.created_at DESC))[1] - (ARRAY_AGG(s.trending_score ORDER BY s.created_at ASC))[1] AS score_change`).
+			Group("t.serial, t.name, ts.usage_count")
+	} else {
+		db = db.Select("t.serial, t.name, ts.usage_count, ts.trending_score")
+	}
+
+	if err := db.Order("trending_score DESC, t.serial ASC").Scan(&trendingTags).Error; err != nil {
+		return nil, fmt.Errorf("error repo get trending tags: %s", err.Error())
+	}
+
+	return trendingTags, nil
+}
+
+func (r *tagRepository) GetTagTrendingSnapshots(req *entity.GetTagTrendRequest) ([]*entity.TagTrendingSnapshot, error) {
+	snapshots := []*entity.TagTrendingSnapshot{}
+
+	err := r.gormDB.Table("tag_trending_snapshots").
+		Select("usage_count, trending_score, created_at").
+		Where("tag_serial = ? AND created_at >= ? AND created_at <= ?", req.TagSerial, req.From, req.To).
+		Order("created_at ASC").
+		Scan(&snapshots).Error
+	if err != nil {
+		return nil, fmt.Errorf("error repo get tag trending snapshots: %s", err.Error())
+	}
+
+	return snapshots, nil
+}
+
+func (r *tagRepository) UpdateTagName(tx *gorm.DB, serial, name string) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `UPDATE tags SET name = ? WHERE serial = ?`
+
+	err := conn.Exec(query, name, serial).Error
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == pq.ErrorCode(r.cfg.PSQLUniqueViolationErrorCode) {
+			return errorutil.NewCustomError(errorutil.ErrConflict, fmt.Errorf("error update tag name: tag name '%s' has exist", name))
+		}
+		return fmt.Errorf("error repo update tag name: %v", err.Error())
+	}
+
+	return nil
+}
+
+// get all tag serials of published versions that use the tag, mapped by version serial.
+// Empty tag serial gets the tag serials of every published version
+func (r *tagRepository) GetPublishedVersionTagSerials(tx *gorm.DB, tagSerial string) (map[string][]string, error) {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `
+		SELECT vt.version_serial, vt.tag_serial
+		FROM version_tags vt
+		INNER JOIN versions v ON v.serial = vt.version_serial
+		WHERE v.status = ?
+		AND (? = '' OR vt.version_serial IN (SELECT version_serial FROM version_tags WHERE tag_serial = ?))
+		ORDER BY vt.version_serial, vt.tag_serial
+	`
+
+	versionTags := []*struct {
+		VersionSerial string
+		TagSerial     string
+	}{}
+	err := conn.Raw(query, entity.VersionStatusPublished.String(), tagSerial, tagSerial).Scan(&versionTags).Error
+	if err != nil {
+		return nil, fmt.Errorf("error repo get published version tag serials: %s", err.Error())
+	}
+
+	mapVersionTags := make(map[string][]string)
+	for _, vt := range versionTags {
+		mapVersionTags[vt.VersionSerial] = append(mapVersionTags[vt.VersionSerial], vt.TagSerial)
+	}
+
+	return mapVersionTags, nil
+}
+
+// move every version of the source tag to the target tag, versions that already have the target tag keep only one
+func (r *tagRepository) MergeVersionTags(tx *gorm.DB, sourceSerial, targetSerial string) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `
+		INSERT INTO version_tags (version_serial, tag_serial)
+		SELECT version_serial, ? FROM version_tags WHERE tag_serial = ?
+		ON CONFLICT (version_serial, tag_serial) DO NOTHING
+	`
+	err := conn.Exec(query, targetSerial, sourceSerial).Error
+	if err != nil {
+		return fmt.Errorf("error repo merge version tags: %s", err.Error())
+	}
+
+	return r.DeleteVersionTags(tx, sourceSerial)
+}
+
+func (r *tagRepository) DeleteVersionTags(tx *gorm.DB, tagSerial string) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	err := conn.Exec(`DELETE FROM version_tags WHERE tag_serial = ?`, tagSerial).Error
+	if err != nil {
+		return fmt.Errorf("error repo delete version tags: %s", err.Error())
+	}
+
+	return nil
+}
+
+// recount the usage count of the tag and its pairs from the published versions
+func (r *tagRepository) RecountTagStats(tx *gorm.DB, tagSerial string) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `
+		UPDATE tag_stats SET usage_count = (
+			SELECT COUNT(*) FROM version_tags vt
+			INNER JOIN versions v ON v.serial = vt.version_serial
+			WHERE vt.tag_serial = ? AND v.status = ?
+		), usage_count_updated_at = NOW()
+		WHERE tag_serial = ?
+	`
+	err := conn.Exec(query, tagSerial, entity.VersionStatusPublished.String(), tagSerial).Error
+	if err != nil {
+		return fmt.Errorf("error repo recount tag stats: %s", err.Error())
+	}
+
+	err = conn.Exec(`DELETE FROM tag_pair_stats WHERE tag1_serial = ? OR tag2_serial = ?`, tagSerial, tagSerial).Error
+	if err != nil {
+		return fmt.Errorf("error repo recount tag stats: %s", err.Error())
+	}
+
+	// pair is ordered with tag1_serial < tag2_serial by byte order, same as generatePairCombination
+	query = `
+		INSERT INTO tag_pair_stats (tag1_serial, tag2_serial, usage_count, updated_at)
+		SELECT LEAST(vt1.tag_serial COLLATE "C", vt2.tag_serial COLLATE "C"),
+			GREATEST(vt1.tag_serial COLLATE "C", vt2.tag_serial COLLATE "C"),
+			COUNT(*), NOW()
+		FROM version_tags vt1
+		INNER JOIN version_tags vt2 ON vt2.version_serial = vt1.version_serial AND vt2.tag_serial <> vt1.tag_serial
+		INNER JOIN versions v ON v.serial = vt1.version_serial
+		WHERE vt1.tag_serial = ? AND v.status = ?
+		GROUP BY 1, 2
+	`
+	err = conn.Exec(query, tagSerial, entity.VersionStatusPublished.String()).Error
+	if err != nil {
+		return fmt.Errorf("error repo recount tag stats: %s", err.Error())
+	}
+
+	return nil
+}
+
+// get the top tags by usage count with their stats
+func (r *tagRepository) GetTagGraphNodes(limit int) ([]*entity.TagGraphNode, error) {
+	query := `
+		SELECT t.serial, t.name, t.parent_serial,
+			COALESCE(ts.usage_count, 0) AS usage_count, COALESCE(ts.trending_score, 0) AS trending_score
+		FROM tags t
+		LEFT JOIN tag_stats ts ON ts.tag_serial = t.serial
+		ORDER BY usage_count DESC, trending_score DESC, t.serial
+		LIMIT ?
+	`
+
+	nodes := []*entity.TagGraphNode{}
+	err := r.gormDB.Raw(query, limit).Scan(&nodes).Error
+	if err != nil {
+		return nil, fmt.Errorf("error repo get tag graph nodes: %s", err.Error())
+	}
+
+	return nodes, nil
+}
+
+// rebuild the usage count of every tag and all tag pair stats from the published versions,
+// the usage count updated time is kept for tags whose count is already right so the trending score does not reset
+func (r *tagRepository) RebuildTagStats(tx *gorm.DB) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	err := conn.Exec(`INSERT INTO tag_stats (tag_serial) SELECT serial FROM tags ON CONFLICT (tag_serial) DO NOTHING`).Error
+	if err != nil {
+		return fmt.Errorf("error repo rebuild tag stats: %s", err.Error())
+	}
+
+	query := `
+		UPDATE tag_stats ts SET
+			usage_count = c.usage_count,
+			usage_count_updated_at = CASE WHEN ts.usage_count <> c.usage_count THEN NOW() ELSE ts.usage_count_updated_at END
+		FROM (
+			SELECT t.serial AS tag_serial, COUNT(v.serial) AS usage_count
+			FROM tags t
+			LEFT JOIN version_tags vt ON vt.tag_serial = t.serial
+			LEFT JOIN versions v ON v.serial = vt.version_serial AND v.status = ?
+			GROUP BY t.serial
+		) c
+		WHERE ts.tag_serial = c.tag_serial
+	`
+	err = conn.Exec(query, entity.VersionStatusPublished.String()).Error
+	if err != nil {
+		return fmt.Errorf("error repo rebuild tag stats: %s", err.Error())
+	}
+
+	err = conn.Exec(`DELETE FROM tag_pair_stats`).Error
+	if err != nil {
+		return fmt.Errorf("error repo rebuild tag stats: %s", err.Error())
+	}
+
+	// pair is ordered with tag1_serial < tag2_serial by byte order, same as generatePairCombination
+	query = `
+		INSERT INTO tag_pair_stats (tag1_serial, tag2_serial, usage_count, updated_at)
+		SELECT vt1.tag_serial, vt2.tag_serial, COUNT(*), NOW()
+		FROM version_tags vt1
+		INNER JOIN version_tags vt2 ON vt2.version_serial = vt1.version_serial
+			AND vt1.tag_serial COLLATE "C" < vt2.tag_serial COLLATE "C"
+		INNER JOIN versions v ON v.serial = vt1.version_serial
+		WHERE v.status = ?
+		GROUP BY vt1.tag_serial, vt2.tag_serial
+	`
+	err = conn.Exec(query, entity.VersionStatusPublished.String()).Error
+	if err != nil {
+		return fmt.Errorf("error repo rebuild tag stats: %s", err.Error())
+	}
+
+	return nil
+}
+
+// move the children of a tag under another parent (or to the root when nil), excluding the given tag
+func (r *tagRepository) MoveTagChildren(tx *gorm.DB, parentSerial string, newParentSerial *string, excludeSerial string) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `UPDATE tags SET parent_serial = ? WHERE parent_serial = ? AND serial <> ?`
+
+	err := conn.Exec(query, newParentSerial, parentSerial, excludeSerial).Error
+	if err != nil {
+		return fmt.Errorf("error repo move tag children: %s", err.Error())
+	}
+
+	return nil
+}
+
+// delete the tag with its stats, the tag must not be used by any version nor be a parent anymore
+func (r *tagRepository) DeleteTag(tx *gorm.DB, serial string) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	queries := []string{
+		`DELETE FROM tag_synonyms WHERE tag_serial = @serial`,
+		`DELETE FROM tag_pair_stats WHERE tag1_serial = @serial OR tag2_serial = @serial`,
+		`DELETE FROM tag_stats WHERE tag_serial = @serial`,
+		`DELETE FROM tag_trending_snapshots WHERE tag_serial = @serial`,
+		`DELETE FROM tag_lexeme_stats WHERE tag_serial = @serial`,
+		`DELETE FROM tags WHERE serial = @serial`,
+	}
+	for _, query := range queries {
+		err := conn.Exec(query, sql.Named("serial", serial)).Error
+		if err != nil {
+			return fmt.Errorf("error repo delete tag: %s", err.Error())
+		}
+	}
+
+	return nil
+}
+
+// map every value that is a tag serial, a tag name or a synonym (names are case-insensitive) to the tag serial,
+// values that match nothing are left out
+func (r *tagRepository) GetTagSerialsByValues(values []string) (map[string]string, error) {
+	mapValueSerial := make(map[string]string)
+	if len(values) == 0 {
+		return mapValueSerial, nil
+	}
+
+	lowerValues := []string{}
+	for _, value := range values {
+		lowerValues = append(lowerValues, strings.ToLower(value))
+	}
+
+	tags := []*entity.Tag{}
+	err := r.gormDB.Table("tags").
+		Select("serial, name").
+		Where("serial IN ? OR LOWER(name) IN ?", values, lowerValues).
+		Scan(&tags).Error
+	if err != nil {
+		return nil, fmt.Errorf("error repo get tag serials by values: %s", err.Error())
+	}
+
+	synonyms := []*entity.TagSynonym{}
+	err = r.gormDB.Table("tag_synonyms").
+		Select("name, tag_serial").
+		Where("LOWER(name) IN ?", lowerValues).
+		Scan(&synonyms).Error
+	if err != nil {
+		return nil, fmt.Errorf("error repo get tag serials by values: %s", err.Error())
+	}
+
+	mapSerial := make(map[string]string)
+	mapName := make(map[string]string)
+	for _, tag := range tags {
+		mapSerial[tag.Serial] = tag.Serial
+		mapName[strings.ToLower(tag.Name)] = tag.Serial
+	}
+	for _, synonym := range synonyms {
+		mapName[strings.ToLower(synonym.Name)] = synonym.TagSerial
+	}
+
+	// serial takes precedence over name
+	for _, value := range values {
+		if serial, ok := mapSerial[value]; ok {
+			mapValueSerial[value] = serial
+		} else if serial, ok := mapName[strings.ToLower(value)]; ok {
+			mapValueSerial[value] = serial
+		}
+	}
+
+	return mapValueSerial, nil
+}
+
+func (r *tagRepository) InsertTagSynonym(tx *gorm.DB, synonym *entity.TagSynonym) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `INSERT INTO tag_synonyms (name, tag_serial) VALUES (?, ?)`
+
+	err := conn.Exec(query, synonym.Name, synonym.TagSerial).Error
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == pq.ErrorCode(r.cfg.PSQLUniqueViolationErrorCode) {
+			return errorutil.NewCustomError(errorutil.ErrConflict, fmt.Errorf("error insert tag synonym: synonym '%s' has exist", synonym.Name))
+		}
+		return fmt.Errorf("error repo insert tag synonym: %v", err.Error())
+	}
+
+	return nil
+}
+
+func (r *tagRepository) GetTagSynonyms(tagSerial string) ([]*entity.TagSynonym, error) {
+	synonyms := []*entity.TagSynonym{}
+
+	err := r.gormDB.Table("tag_synonyms").
+		Select("name, tag_serial, created_at").
+		Where("tag_serial = ?", tagSerial).
+		Order("name ASC").
+		Scan(&synonyms).Error
+	if err != nil {
+		return nil, fmt.Errorf("error repo get tag synonyms: %s", err.Error())
+	}
+
+	return synonyms, nil
+}
+
+func (r *tagRepository) DeleteTagSynonym(tagSerial, name string) error {
+	result := r.gormDB.Exec(`DELETE FROM tag_synonyms WHERE tag_serial = ? AND LOWER(name) = LOWER(?)`, tagSerial, name)
+	if result.Error != nil {
+		return fmt.Errorf("error repo delete tag synonym: %s", result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error delete tag synonym: synonym '%s' of tag '%s' is not found", name, tagSerial))
+	}
+
+	return nil
+}
+
+func (r *tagRepository) MoveTagSynonyms(tx *gorm.DB, sourceSerial, targetSerial string) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	err := conn.Exec(`UPDATE tag_synonyms SET tag_serial = ? WHERE tag_serial = ?`, targetSerial, sourceSerial).Error
+	if err != nil {
+		return fmt.Errorf("error repo move tag synonyms: %s", err.Error())
+	}
+
+	return nil
+}
+
+// get pair stats that have any of the tags on either side
+func (r *tagRepository) GetTagPairStatsByAnySerials(serials []string) ([]*entity.TagPairStat, error) {
+	tagPairStats := []*entity.TagPairStat{}
+	if len(serials) == 0 {
+		return tagPairStats, nil
+	}
+
+	err := r.gormDB.Table("tag_pair_stats").
+		Where("(tag1_serial IN ? OR tag2_serial IN ?) AND usage_count > 0", serials, serials).
+		Scan(&tagPairStats).Error
+	if err != nil {
+		return nil, fmt.Errorf("error repo get tag pair stats by any serials: %s", err.Error())
+	}
+
+	return tagPairStats, nil
+}
+
+func (r *tagRepository) GetTagsBySerials(serials []string) ([]*entity.Tag, error) {
+	tags := []*entity.Tag{}
+	if len(serials) == 0 {
+		return tags, nil
+	}
+
+	err := r.gormDB.Table("tags").
+		Select("serial, name, parent_serial").
+		Where("serial IN ?", serials).
+		Scan(&tags).Error
+	if err != nil {
+		return nil, fmt.Errorf("error repo get tags by serials: %s", err.Error())
+	}
+
+	return tags, nil
+}
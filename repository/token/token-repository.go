@@ -0,0 +1,176 @@
+package tokenrepository
+
+import (
+	"article-versioning-api/config"
+	"article-versioning-api/core/entity"
+	"article-versioning-api/core/repository"
+	transactionutil "article-versioning-api/utils/transaction"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type tokenRepository struct {
+	cfg    *config.Config
+	gormDB *gorm.DB
+}
+
+func NewTokenRepository(cfg *config.Config, gormDB *gorm.DB) repository.TokenRepositoryInterface {
+	return &tokenRepository{cfg, gormDB}
+}
+
+func (r *tokenRepository) InsertRefreshToken(tx *gorm.DB, token *entity.RefreshToken) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `INSERT INTO refresh_tokens (token_hash, family_id, username, access_token_id, expires_at)
+		VALUES (?, ?, ?, ?, ?)`
+
+	err := conn.Exec(query, token.TokenHash, token.FamilyID, token.Username, token.AccessTokenID, token.ExpiresAt.UTC()).Error
+	if err != nil {
+		return fmt.Errorf("error repo insert refresh token: %s", err.Error())
+	}
+
+	return nil
+}
+
+// get the refresh token and lock it until the transaction ends, so it can only be rotated once
+func (r *tokenRepository) GetRefreshTokenByHashForUpdate(tx *gorm.DB, tokenHash string) (*entity.RefreshToken, error) {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `SELECT token_hash, family_id, username, access_token_id, expires_at, created_at, used_at, revoked_at
+		FROM refresh_tokens WHERE token_hash = ? FOR UPDATE`
+
+	token := &entity.RefreshToken{}
+	result := conn.Raw(query, tokenHash).Scan(token)
+	if result.Error != nil {
+		return nil, fmt.Errorf("error repo get refresh token: %s", result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return token, nil
+}
+
+func (r *tokenRepository) UpdateRefreshTokenUsed(tx *gorm.DB, tokenHash string) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `UPDATE refresh_tokens SET used_at = NOW() AT TIME ZONE 'UTC' WHERE token_hash = ?`
+
+	err := conn.Exec(query, tokenHash).Error
+	if err != nil {
+		return fmt.Errorf("error repo update refresh token used: %s", err.Error())
+	}
+
+	return nil
+}
+
+// revoke every refresh token of the family and the access tokens issued with them
+func (r *tokenRepository) RevokeRefreshTokenFamily(tx *gorm.DB, familyID string, accessTokenExpiresAt time.Time) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `UPDATE refresh_tokens SET revoked_at = NOW() AT TIME ZONE 'UTC' WHERE family_id = ? AND revoked_at IS NULL`
+
+	err := conn.Exec(query, familyID).Error
+	if err != nil {
+		return fmt.Errorf("error repo revoke refresh token family: %s", err.Error())
+	}
+
+	// the access tokens expire at the latest one access token TTL from now
+	query = `INSERT INTO revoked_tokens (token_id, expires_at)
+		SELECT access_token_id, ? FROM refresh_tokens WHERE family_id = ?
+		ON CONFLICT (token_id) DO NOTHING`
+
+	err = conn.Exec(query, accessTokenExpiresAt.UTC(), familyID).Error
+	if err != nil {
+		return fmt.Errorf("error repo revoke refresh token family: %s", err.Error())
+	}
+
+	return nil
+}
+
+// revoke every refresh token of the user and the access tokens issued with them
+func (r *tokenRepository) RevokeUserTokens(tx *gorm.DB, username string, accessTokenExpiresAt time.Time) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `INSERT INTO revoked_tokens (token_id, expires_at)
+		SELECT access_token_id, ? FROM refresh_tokens WHERE username = ? AND revoked_at IS NULL AND expires_at > NOW() AT TIME ZONE 'UTC'
+		ON CONFLICT (token_id) DO NOTHING`
+
+	err := conn.Exec(query, accessTokenExpiresAt.UTC(), username).Error
+	if err != nil {
+		return fmt.Errorf("error repo revoke user tokens: %s", err.Error())
+	}
+
+	query = `UPDATE refresh_tokens SET revoked_at = NOW() AT TIME ZONE 'UTC' WHERE username = ? AND revoked_at IS NULL`
+
+	err = conn.Exec(query, username).Error
+	if err != nil {
+		return fmt.Errorf("error repo revoke user tokens: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (r *tokenRepository) InsertRevokedToken(tx *gorm.DB, tokenID string, expiresAt time.Time) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `INSERT INTO revoked_tokens (token_id, expires_at) VALUES (?, ?) ON CONFLICT (token_id) DO NOTHING`
+
+	err := conn.Exec(query, tokenID, expiresAt.UTC()).Error
+	if err != nil {
+		return fmt.Errorf("error repo insert revoked token: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (r *tokenRepository) IsTokenRevoked(tokenID string) (bool, error) {
+	var count int64
+	err := r.gormDB.Table("revoked_tokens").Where("token_id = ?", tokenID).Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("error repo check revoked token: %s", err.Error())
+	}
+
+	return count > 0, nil
+}
+
+// delete the refresh tokens and revoked access tokens that are expired, an expired token is rejected anyway
+func (r *tokenRepository) DeleteExpiredTokens(tx *gorm.DB) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	queries := []string{
+		`DELETE FROM refresh_tokens WHERE expires_at < NOW() AT TIME ZONE 'UTC'`,
+		`DELETE FROM revoked_tokens WHERE expires_at < NOW() AT TIME ZONE 'UTC'`,
+	}
+	for _, query := range queries {
+		err := conn.Exec(query).Error
+		if err != nil {
+			return fmt.Errorf("error repo delete expired tokens: %s", err.Error())
+		}
+	}
+
+	return nil
+}
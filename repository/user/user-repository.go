@@ -5,29 +5,38 @@ import (
 	"article-versioning-api/core/entity"
 	"article-versioning-api/core/repository"
 	"database/sql"
+	"errors"
 	"fmt"
 
 	errorutil "article-versioning-api/utils/error"
+	transactionutil "article-versioning-api/utils/transaction"
 
 	"github.com/lib/pq"
+	"gorm.io/gorm"
 )
 
 type userRepository struct {
-	Db *sql.DB
-	cfg *config.Config
+	Db     *sql.DB
+	cfg    *config.Config
+	gormDB *gorm.DB
 }
 
-func NewUserRepository(Db *sql.DB, cfg *config.Config) repository.UserRepositoryInterface {
-	return &userRepository{Db, cfg}
+func NewUserRepository(Db *sql.DB, cfg *config.Config, gormDB *gorm.DB) repository.UserRepositoryInterface {
+	return &userRepository{Db, cfg, gormDB}
 }
 
+func (r *userRepository) CreateUser(tx *gorm.DB, req *entity.RegisterUserRequest) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
 
-func (r *userRepository) CreateUser(req *entity.RegisterUserRequest) error {
-	query := `INSERT INTO users (username, role, hash) VALUES ($1, $2, $3)`
+	query := `INSERT INTO users (username, role, hash) VALUES (?, ?, ?)`
 
-	_, err := r.Db.Exec(query, req.Username, req.Role, req.Hash)
+	err := conn.Exec(query, req.Username, req.Role, req.Hash).Error
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == pq.ErrorCode(r.cfg.PSQLUniqueViolationErrorCode) {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == pq.ErrorCode(r.cfg.PSQLUniqueViolationErrorCode) {
 			return errorutil.NewCustomError(errorutil.ErrBadRequest, fmt.Errorf("error create user: username has exist"))
 		} else {
 			return fmt.Errorf("error repo create user: %v", err.Error())
@@ -38,14 +47,245 @@ func (r *userRepository) CreateUser(req *entity.RegisterUserRequest) error {
 }
 
 func (r *userRepository) GetUserByUsername(username string) (*entity.User, error) {
-	query := `SELECT username, role, hash FROM users WHERE username = $1`
+	query := `SELECT username, role, hash, is_active, password_reset_required FROM users WHERE username = $1`
 
 	user := &entity.User{}
 
-	err := r.Db.QueryRow(query, username).Scan(&user.Username, &user.Role, &user.Hash)
+	err := r.Db.QueryRow(query, username).Scan(&user.Username, &user.Role, &user.Hash, &user.IsActive, &user.PasswordResetRequired)
 	if err != nil {
 		return nil, fmt.Errorf("error repo create user: %s", err.Error())
 	}
 
 	return user, nil
 }
+
+// get the user and lock it until the transaction ends, return nil if it is not found
+func (r *userRepository) GetUserByUsernameForUpdate(tx *gorm.DB, username string) (*entity.User, error) {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `SELECT username, role, hash, is_active, password_reset_required FROM users WHERE username = ? FOR UPDATE`
+
+	user := &entity.User{}
+	result := conn.Raw(query, username).Scan(user)
+	if result.Error != nil {
+		return nil, fmt.Errorf("error repo get user: %s", result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return user, nil
+}
+
+func (r *userRepository) GetUsers(req *entity.GetUsersRequest) (*entity.GetUsersResponse, error) {
+	users := []*entity.UserDetail{}
+
+	db := r.gormDB.Table("users")
+	if req.Query != "" {
+		db = db.Where("username ILIKE ?", "%"+req.Query+"%")
+	}
+	if req.Role != "" {
+		db = db.Where("role = ?", req.Role)
+	}
+	if req.IsActive != nil {
+		db = db.Where("is_active = ?", *req.IsActive)
+	}
+
+	var total int64
+	if req.Pagination != nil {
+		if err := db.Count(&total).Error; err != nil {
+			return nil, fmt.Errorf("error repo get users: %s", err.Error())
+		}
+		if total == 0 {
+			return &entity.GetUsersResponse{
+				Users:      users,
+				Pagination: &entity.Pagination{},
+			}, nil
+		}
+		req.Pagination.Total = int(total)
+
+		req.Pagination.SetPagination()
+		db = db.Limit(req.Pagination.PageSize).Offset(req.Pagination.GetOffset())
+	}
+
+	err := db.Select("username, role, is_active, password_reset_required, created_at, updated_at").
+		Order("username ASC").Scan(&users).Error
+	if err != nil {
+		return nil, fmt.Errorf("error repo get users: %s", err.Error())
+	}
+
+	return &entity.GetUsersResponse{
+		Users:      users,
+		Pagination: req.Pagination,
+	}, nil
+}
+
+func (r *userRepository) UpdateUserRole(tx *gorm.DB, username, role string) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `UPDATE users SET role = ?, updated_at = NOW() WHERE username = ?`
+
+	err := conn.Exec(query, role, username).Error
+	if err != nil {
+		return fmt.Errorf("error repo update user role: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (r *userRepository) UpdateUserActive(tx *gorm.DB, username string, isActive bool) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `UPDATE users SET is_active = ?, updated_at = NOW() WHERE username = ?`
+
+	err := conn.Exec(query, isActive, username).Error
+	if err != nil {
+		return fmt.Errorf("error repo update user active: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (r *userRepository) UpdateUserPassword(tx *gorm.DB, username, hash string, resetRequired bool) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `UPDATE users SET hash = ?, password_reset_required = ?, updated_at = NOW() WHERE username = ?`
+
+	err := conn.Exec(query, hash, resetRequired, username).Error
+	if err != nil {
+		return fmt.Errorf("error repo update user password: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (r *userRepository) InsertUserAuditLog(tx *gorm.DB, log *entity.UserAuditLog) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `INSERT INTO user_audit_logs (target_username, actor_username, action, old_value, new_value) VALUES (?, ?, ?, ?, ?)`
+
+	err := conn.Exec(query, log.TargetUsername, log.ActorUsername, log.Action, log.OldValue, log.NewValue).Error
+	if err != nil {
+		return fmt.Errorf("error repo insert user audit log: %s", err.Error())
+	}
+
+	return nil
+}
+
+// get audit logs of the user, latest first
+func (r *userRepository) GetUserAuditLogs(req *entity.GetUserAuditLogsRequest) (*entity.GetUserAuditLogsResponse, error) {
+	logs := []*entity.UserAuditLog{}
+
+	db := r.gormDB.Table("user_audit_logs").Where("target_username = ?", req.Username)
+
+	var total int64
+	if req.Pagination != nil {
+		if err := db.Count(&total).Error; err != nil {
+			return nil, fmt.Errorf("error repo get user audit logs: %s", err.Error())
+		}
+		if total == 0 {
+			return &entity.GetUserAuditLogsResponse{
+				Logs:       logs,
+				Pagination: &entity.Pagination{},
+			}, nil
+		}
+		req.Pagination.Total = int(total)
+
+		req.Pagination.SetPagination()
+		db = db.Limit(req.Pagination.PageSize).Offset(req.Pagination.GetOffset())
+	}
+
+	err := db.Order("created_at DESC, id DESC").Scan(&logs).Error
+	if err != nil {
+		return nil, fmt.Errorf("error repo get user audit logs: %s", err.Error())
+	}
+
+	return &entity.GetUserAuditLogsResponse{
+		Logs:       logs,
+		Pagination: req.Pagination,
+	}, nil
+}
+
+func (r *userRepository) CountUsersByRole(tx *gorm.DB, role string) (int64, error) {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	var count int64
+	err := conn.Table("users").Where("role = ?", role).Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("error repo count users by role: %s", err.Error())
+	}
+
+	return count, nil
+}
+
+func (r *userRepository) InsertInvitation(tx *gorm.DB, invitation *entity.Invitation) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `INSERT INTO user_invitations (token_hash, role, username, created_by_username, expires_at) VALUES (?, ?, ?, ?, ?)`
+
+	err := conn.Exec(query, invitation.TokenHash, invitation.Role, invitation.Username, invitation.CreatedByUsername, invitation.ExpiresAt.UTC()).Error
+	if err != nil {
+		return fmt.Errorf("error repo insert invitation: %s", err.Error())
+	}
+
+	return nil
+}
+
+// get the invitation and lock it until the transaction ends, so it can only be used once. Return nil if it is not found
+func (r *userRepository) GetInvitationByHashForUpdate(tx *gorm.DB, tokenHash string) (*entity.Invitation, error) {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `SELECT token_hash, role, username, created_by_username, expires_at, created_at, used_at, used_by_username
+		FROM user_invitations WHERE token_hash = ? FOR UPDATE`
+
+	invitation := &entity.Invitation{}
+	result := conn.Raw(query, tokenHash).Scan(invitation)
+	if result.Error != nil {
+		return nil, fmt.Errorf("error repo get invitation: %s", result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return invitation, nil
+}
+
+func (r *userRepository) UpdateInvitationUsed(tx *gorm.DB, tokenHash, username string) error {
+	conn := transactionutil.GetTransaction(tx)
+	if conn == nil {
+		conn = r.gormDB
+	}
+
+	query := `UPDATE user_invitations SET used_at = NOW() AT TIME ZONE 'UTC', used_by_username = ? WHERE token_hash = ?`
+
+	err := conn.Exec(query, username, tokenHash).Error
+	if err != nil {
+		return fmt.Errorf("error repo update invitation used: %s", err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,508 @@
+package diffutil
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type OpType string
+
+const (
+	OpEqual  OpType = "equal"
+	OpInsert OpType = "insert"
+	OpDelete OpType = "delete"
+
+	DefaultContextLines = 3
+)
+
+// Op is a single step of an edit script, OldIndex and NewIndex are 0-based positions in the compared slices (-1 when not applicable)
+type Op struct {
+	Type     OpType
+	OldIndex int
+	NewIndex int
+	Text     string
+}
+
+type Segment struct {
+	Type OpType `json:"type"`
+	Text string `json:"text"`
+}
+
+type Line struct {
+	Type      OpType     `json:"type"`
+	OldNumber int        `json:"oldNumber,omitempty"`
+	NewNumber int        `json:"newNumber,omitempty"`
+	Content   string     `json:"content"`
+	Words     []*Segment `json:"words,omitempty"`
+}
+
+type Hunk struct {
+	OldStart int     `json:"oldStart"`
+	OldLines int     `json:"oldLines"`
+	NewStart int     `json:"newStart"`
+	NewLines int     `json:"newLines"`
+	Lines    []*Line `json:"lines"`
+}
+
+type TextDiff struct {
+	Unified string  `json:"unified"`
+	Hunks   []*Hunk `json:"hunks"`
+}
+
+// Diff returns the shortest edit script that turns a into b (Myers' algorithm in linear space),
+// the deletes of a changed region come before its inserts
+func Diff(a, b []string) []Op {
+	d := &differ{a: a, b: b, ops: make([]Op, 0, len(a)+len(b))}
+	maxD := (len(a)+len(b)+1)/2 + 1
+	d.forward = make([]int, 2*maxD+1)
+	d.backward = make([]int, 2*maxD+1)
+	d.compare(0, len(a), 0, len(b))
+
+	return orderChanges(d.ops)
+}
+
+type differ struct {
+	a, b []string
+	ops  []Op
+
+	// furthest x reached on each diagonal k = x - y, reused by every middle snake search
+	forward  []int
+	backward []int
+}
+
+// compare appends the edit script of a[aLo:aHi] and b[bLo:bHi], it splits the problem at the middle snake
+// so only the diagonals of one search are kept in memory
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.ops = append(d.ops, Op{Type: OpEqual, OldIndex: aLo, NewIndex: bLo, Text: d.a[aLo]})
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && d.a[aHi-suffix-1] == d.b[bHi-suffix-1] {
+		suffix++
+	}
+	aHi -= suffix
+	bHi -= suffix
+
+	switch {
+	case aLo == aHi:
+		for j := bLo; j < bHi; j++ {
+			d.ops = append(d.ops, Op{Type: OpInsert, OldIndex: -1, NewIndex: j, Text: d.b[j]})
+		}
+	case bLo == bHi:
+		for i := aLo; i < aHi; i++ {
+			d.ops = append(d.ops, Op{Type: OpDelete, OldIndex: i, NewIndex: -1, Text: d.a[i]})
+		}
+	default:
+		x, y, u, v := d.middleSnake(aLo, aHi, bLo, bHi)
+		d.compare(aLo, x, bLo, y)
+		for ; x < u; x, y = x+1, y+1 {
+			d.ops = append(d.ops, Op{Type: OpEqual, OldIndex: x, NewIndex: y, Text: d.a[x]})
+		}
+		d.compare(u, aHi, v, bHi)
+	}
+
+	for k := 0; k < suffix; k++ {
+		d.ops = append(d.ops, Op{Type: OpEqual, OldIndex: aHi + k, NewIndex: bHi + k, Text: d.a[aHi+k]})
+	}
+}
+
+// middleSnake searches the shortest edit path from both ends at once and returns the snake (x, y) to (u, v)
+// where the searches meet, the edits before and after it are at most half of the edits each
+func (d *differ) middleSnake(aLo, aHi, bLo, bHi int) (int, int, int, int) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	maxD := (n + m + 1) / 2
+
+	// diagonals are relative to a[aLo] and b[bLo], fo and bo map a diagonal to its index, the backward search starts on delta
+	forward, backward := d.forward, d.backward
+	fo := len(forward) / 2
+	bo := fo - delta
+
+	forward[1+fo] = 0
+	backward[delta-1+bo] = n
+	for step := 0; step <= maxD; step++ {
+		for k := -step; k <= step; k += 2 {
+			var x int
+			if k == -step || (k != step && forward[k-1+fo] < forward[k+1+fo]) {
+				x = forward[k+1+fo]
+			} else {
+				x = forward[k-1+fo] + 1
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+			}
+			forward[k+fo] = x
+
+			if odd && k >= delta-(step-1) && k <= delta+(step-1) && x >= backward[k+bo] {
+				return aLo + startX, bLo + startY, aLo + x, bLo + y
+			}
+		}
+
+		for k := delta - step; k <= delta+step; k += 2 {
+			var x int
+			if k == delta+step || (k != delta-step && backward[k-1+bo] < backward[k+1+bo]-1) {
+				x = backward[k-1+bo]
+			} else {
+				x = backward[k+1+bo] - 1
+			}
+			y := x - k
+			endX, endY := x, y
+			for x > 0 && y > 0 && d.a[aLo+x-1] == d.b[bLo+y-1] {
+				x--
+				y--
+			}
+			backward[k+bo] = x
+
+			if !odd && k >= -step && k <= step && x <= forward[k+fo] {
+				return aLo + x, bLo + y, aLo + endX, bLo + endY
+			}
+		}
+	}
+
+	// unreachable, the searches always meet within maxD steps
+	return aLo, bLo, aLo, bLo
+}
+
+// orderChanges moves the deletes of every changed region before its inserts, so replaced lines can be paired
+func orderChanges(ops []Op) []Op {
+	ordered := make([]Op, 0, len(ops))
+	inserts := []Op{}
+	for _, op := range ops {
+		switch op.Type {
+		case OpInsert:
+			inserts = append(inserts, op)
+		case OpDelete:
+			ordered = append(ordered, op)
+		default:
+			ordered = append(ordered, inserts...)
+			inserts = inserts[:0]
+			ordered = append(ordered, op)
+		}
+	}
+
+	return append(ordered, inserts...)
+}
+
+// SplitLines splits text into lines, an empty text has no lines
+func SplitLines(text string) []string {
+	if text == "" {
+		return []string{}
+	}
+	return strings.Split(text, "\n")
+}
+
+// SplitWords splits text into alternating runs of whitespace and non whitespace, joining the result gives back the text
+func SplitWords(text string) []string {
+	words := []string{}
+
+	var sb strings.Builder
+	inSpace := false
+	for i, r := range text {
+		isSpace := unicode.IsSpace(r)
+		if i > 0 && isSpace != inSpace {
+			words = append(words, sb.String())
+			sb.Reset()
+		}
+		sb.WriteRune(r)
+		inSpace = isSpace
+	}
+	if sb.Len() > 0 {
+		words = append(words, sb.String())
+	}
+
+	return words
+}
+
+// Words returns the word-level diff between a and b, consecutive segments of the same type are merged
+func Words(a, b string) []*Segment {
+	segments := []*Segment{}
+	for _, op := range Diff(SplitWords(a), SplitWords(b)) {
+		if len(segments) > 0 && segments[len(segments)-1].Type == op.Type {
+			segments[len(segments)-1].Text += op.Text
+			continue
+		}
+		segments = append(segments, &Segment{Type: op.Type, Text: op.Text})
+	}
+
+	return segments
+}
+
+// Compare returns the line-level diff between oldText and newText as unified diff text and structured hunks,
+// changed lines that replace each other also carry their word-level diff
+func Compare(oldName, newName, oldText, newText string, contextLines int) *TextDiff {
+	if contextLines < 0 {
+		contextLines = DefaultContextLines
+	}
+
+	ops := Diff(SplitLines(oldText), SplitLines(newText))
+	hunks := buildHunks(ops, contextLines)
+
+	return &TextDiff{
+		Unified: formatUnified(oldName, newName, hunks),
+		Hunks:   hunks,
+	}
+}
+
+func buildHunks(ops []Op, contextLines int) []*Hunk {
+	hunks := []*Hunk{}
+
+	// old and new line numbers (1-based) reached before each op
+	oldNumbers := make([]int, len(ops)+1)
+	newNumbers := make([]int, len(ops)+1)
+	oldNumbers[0], newNumbers[0] = 1, 1
+	for i, op := range ops {
+		oldNumbers[i+1], newNumbers[i+1] = oldNumbers[i], newNumbers[i]
+		if op.Type != OpInsert {
+			oldNumbers[i+1]++
+		}
+		if op.Type != OpDelete {
+			newNumbers[i+1]++
+		}
+	}
+
+	i := 0
+	for i < len(ops) {
+		if ops[i].Type == OpEqual {
+			i++
+			continue
+		}
+
+		// extend the hunk while the next change is within two context windows
+		start := max(i-contextLines, 0)
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].Type == OpEqual {
+				continue
+			}
+			if j-end-1 > 2*contextLines {
+				break
+			}
+			end = j
+		}
+		end = min(end+contextLines+1, len(ops))
+
+		hunk := &Hunk{
+			OldStart: oldNumbers[start],
+			NewStart: newNumbers[start],
+			Lines:    []*Line{},
+		}
+		for k := start; k < end; k++ {
+			line := &Line{Type: ops[k].Type, Content: ops[k].Text}
+			if ops[k].Type != OpInsert {
+				line.OldNumber = oldNumbers[k]
+				hunk.OldLines++
+			}
+			if ops[k].Type != OpDelete {
+				line.NewNumber = newNumbers[k]
+				hunk.NewLines++
+			}
+			hunk.Lines = append(hunk.Lines, line)
+		}
+		// unified diff points an empty range at the line before it
+		if hunk.OldLines == 0 {
+			hunk.OldStart--
+		}
+		if hunk.NewLines == 0 {
+			hunk.NewStart--
+		}
+
+		attachWordDiffs(hunk.Lines)
+		hunks = append(hunks, hunk)
+		i = end
+	}
+
+	return hunks
+}
+
+// pair each deleted line with the inserted line that replaces it and attach their word-level diff
+func attachWordDiffs(lines []*Line) {
+	i := 0
+	for i < len(lines) {
+		if lines[i].Type != OpDelete {
+			i++
+			continue
+		}
+
+		deleteStart := i
+		for i < len(lines) && lines[i].Type == OpDelete {
+			i++
+		}
+		insertStart := i
+		for i < len(lines) && lines[i].Type == OpInsert {
+			i++
+		}
+
+		pairs := min(insertStart-deleteStart, i-insertStart)
+		for k := 0; k < pairs; k++ {
+			oldLine, newLine := lines[deleteStart+k], lines[insertStart+k]
+			words := Words(oldLine.Content, newLine.Content)
+			for _, w := range words {
+				if w.Type != OpInsert {
+					oldLine.Words = append(oldLine.Words, w)
+				}
+				if w.Type != OpDelete {
+					newLine.Words = append(newLine.Words, w)
+				}
+			}
+		}
+	}
+}
+
+func formatUnified(oldName, newName string, hunks []*Hunk) string {
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+	for _, hunk := range hunks {
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", hunk.OldStart, hunk.OldLines, hunk.NewStart, hunk.NewLines)
+		for _, line := range hunk.Lines {
+			switch line.Type {
+			case OpInsert:
+				sb.WriteString("+")
+			case OpDelete:
+				sb.WriteString("-")
+			default:
+				sb.WriteString(" ")
+			}
+			sb.WriteString(line.Content)
+			sb.WriteString("\n")
+		}
+	}
+
+	return sb.String()
+}
+
+const (
+	conflictMarkerOurs   = "<<<<<<<"
+	conflictMarkerBase   = "|||||||"
+	conflictMarkerSplit  = "======="
+	conflictMarkerTheirs = ">>>>>>>"
+)
+
+// Conflict is a region that is changed differently on both sides, StartLine and EndLine are 1-based lines of the merged result including the markers
+type Conflict struct {
+	StartLine int      `json:"startLine"`
+	EndLine   int      `json:"endLine"`
+	Base      []string `json:"base"`
+	Ours      []string `json:"ours"`
+	Theirs    []string `json:"theirs"`
+}
+
+type MergeResult struct {
+	Lines     []string    `json:"lines"`
+	Conflicts []*Conflict `json:"conflicts"`
+}
+
+func (r *MergeResult) HasConflicts() bool {
+	return len(r.Conflicts) > 0
+}
+
+func (r *MergeResult) Text() string {
+	return strings.Join(r.Lines, "\n")
+}
+
+// Merge3 merges the changes of ours and theirs that both descend from base (diff3),
+// regions that are changed differently on both sides are written with conflict markers using the given labels
+func Merge3(base, ours, theirs []string, baseLabel, oursLabel, theirsLabel string) *MergeResult {
+	oursMatch := matchBaseIndexes(base, ours)
+	theirsMatch := matchBaseIndexes(base, theirs)
+
+	result := &MergeResult{
+		Lines:     []string{},
+		Conflicts: []*Conflict{},
+	}
+
+	baseIdx, oursIdx, theirsIdx := 0, 0, 0
+	for baseIdx < len(base) || oursIdx < len(ours) || theirsIdx < len(theirs) {
+		// next base line that is kept by both sides
+		syncIdx := baseIdx
+		for syncIdx < len(base) && (oursMatch[syncIdx] < 0 || theirsMatch[syncIdx] < 0) {
+			syncIdx++
+		}
+
+		oursEnd, theirsEnd := len(ours), len(theirs)
+		if syncIdx < len(base) {
+			oursEnd, theirsEnd = oursMatch[syncIdx], theirsMatch[syncIdx]
+		}
+
+		if syncIdx == baseIdx && oursEnd == oursIdx && theirsEnd == theirsIdx {
+			// stable line
+			result.Lines = append(result.Lines, base[baseIdx])
+			baseIdx++
+			oursIdx++
+			theirsIdx++
+			continue
+		}
+
+		baseChunk := base[baseIdx:syncIdx]
+		oursChunk := ours[oursIdx:oursEnd]
+		theirsChunk := theirs[theirsIdx:theirsEnd]
+
+		switch {
+		case equalLines(oursChunk, baseChunk):
+			result.Lines = append(result.Lines, theirsChunk...)
+		case equalLines(theirsChunk, baseChunk), equalLines(oursChunk, theirsChunk):
+			result.Lines = append(result.Lines, oursChunk...)
+		default:
+			conflict := &Conflict{
+				StartLine: len(result.Lines) + 1,
+				Base:      append([]string{}, baseChunk...),
+				Ours:      append([]string{}, oursChunk...),
+				Theirs:    append([]string{}, theirsChunk...),
+			}
+
+			result.Lines = append(result.Lines, conflictMarkerOurs+" "+oursLabel)
+			result.Lines = append(result.Lines, oursChunk...)
+			result.Lines = append(result.Lines, conflictMarkerBase+" "+baseLabel)
+			result.Lines = append(result.Lines, baseChunk...)
+			result.Lines = append(result.Lines, conflictMarkerSplit)
+			result.Lines = append(result.Lines, theirsChunk...)
+			result.Lines = append(result.Lines, conflictMarkerTheirs+" "+theirsLabel)
+
+			conflict.EndLine = len(result.Lines)
+			result.Conflicts = append(result.Conflicts, conflict)
+		}
+
+		baseIdx, oursIdx, theirsIdx = syncIdx, oursEnd, theirsEnd
+	}
+
+	return result
+}
+
+// for every base line returns the index of the matching line in other, or -1 when it is not kept
+func matchBaseIndexes(base, other []string) []int {
+	matches := make([]int, len(base))
+	for i := range matches {
+		matches[i] = -1
+	}
+
+	for _, op := range Diff(base, other) {
+		if op.Type == OpEqual {
+			matches[op.OldIndex] = op.NewIndex
+		}
+	}
+
+	return matches
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,365 @@
+package diffutil
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// lcsLength is the length of the longest common subsequence, a minimal edit script keeps that many lines
+func lcsLength(a, b []string) int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	return lcs[0][0]
+}
+
+// validateEditScript checks ops turns a into b in order, is minimal and has the deletes of a changed region before its inserts
+func validateEditScript(t *testing.T, a, b []string, ops []Op) {
+	t.Helper()
+
+	i, j, equals := 0, 0, 0
+	for k, op := range ops {
+		switch op.Type {
+		case OpEqual:
+			if op.OldIndex != i || op.NewIndex != j || i >= len(a) || j >= len(b) || a[i] != b[j] || op.Text != a[i] {
+				t.Fatalf("op %d: invalid equal %+v at old %d new %d", k, op, i, j)
+			}
+			i++
+			j++
+			equals++
+		case OpDelete:
+			if op.OldIndex != i || op.NewIndex != -1 || i >= len(a) || op.Text != a[i] {
+				t.Fatalf("op %d: invalid delete %+v at old %d", k, op, i)
+			}
+			if k > 0 && ops[k-1].Type == OpInsert {
+				t.Fatalf("op %d: delete after insert in the same changed region", k)
+			}
+			i++
+		case OpInsert:
+			if op.NewIndex != j || op.OldIndex != -1 || j >= len(b) || op.Text != b[j] {
+				t.Fatalf("op %d: invalid insert %+v at new %d", k, op, j)
+			}
+			j++
+		default:
+			t.Fatalf("op %d: unknown type %s", k, op.Type)
+		}
+	}
+	if i != len(a) || j != len(b) {
+		t.Fatalf("edit script stops at old %d/%d new %d/%d", i, len(a), j, len(b))
+	}
+	if want := lcsLength(a, b); equals != want {
+		t.Fatalf("edit script keeps %d lines, the longest common subsequence has %d", equals, want)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+	}{
+		{name: "both empty", a: []string{}, b: []string{}},
+		{name: "insert into empty", a: []string{}, b: []string{"a", "b"}},
+		{name: "delete everything", a: []string{"a", "b"}, b: []string{}},
+		{name: "equal", a: []string{"a", "b", "c"}, b: []string{"a", "b", "c"}},
+		{name: "replace one line", a: []string{"a"}, b: []string{"b"}},
+		{name: "insert in the middle", a: []string{"a", "c"}, b: []string{"a", "b", "c"}},
+		{name: "delete in the middle", a: []string{"a", "b", "c"}, b: []string{"a", "c"}},
+		{name: "replace in the middle", a: []string{"a", "b", "c"}, b: []string{"a", "x", "y", "c"}},
+		{name: "repeated lines", a: []string{"a", "b", "a", "b", "a"}, b: []string{"b", "a", "b", "a", "b"}},
+		{name: "moved block", a: []string{"a", "b", "c", "d", "e"}, b: []string{"d", "e", "a", "b", "c"}},
+		{name: "nothing in common", a: []string{"a", "b", "c"}, b: []string{"x", "y"}},
+		{name: "odd length difference", a: []string{"a", "b", "c", "d"}, b: []string{"b", "x", "d"}},
+		{name: "even length difference", a: []string{"a", "b", "c", "d", "e", "f"}, b: []string{"a", "x", "d", "f"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validateEditScript(t, tt.a, tt.b, Diff(tt.a, tt.b))
+		})
+	}
+}
+
+func TestDiffDeleteBeforeInsert(t *testing.T) {
+	ops := Diff([]string{"a", "old", "c"}, []string{"a", "new", "c"})
+
+	want := []Op{
+		{Type: OpEqual, OldIndex: 0, NewIndex: 0, Text: "a"},
+		{Type: OpDelete, OldIndex: 1, NewIndex: -1, Text: "old"},
+		{Type: OpInsert, OldIndex: -1, NewIndex: 1, Text: "new"},
+		{Type: OpEqual, OldIndex: 2, NewIndex: 2, Text: "c"},
+	}
+	if !reflect.DeepEqual(ops, want) {
+		t.Fatalf("got %+v, want %+v", ops, want)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name         string
+		oldText      string
+		newText      string
+		contextLines int
+		wantUnified  string
+		wantHunks    int
+	}{
+		{
+			name:        "equal texts have no hunks",
+			oldText:     "a\nb",
+			newText:     "a\nb",
+			wantUnified: "",
+		},
+		{
+			name:        "both empty",
+			oldText:     "",
+			newText:     "",
+			wantUnified: "",
+		},
+		{
+			name:        "add to empty text",
+			oldText:     "",
+			newText:     "a",
+			wantUnified: "--- old\n+++ new\n@@ -0,0 +1,1 @@\n+a\n",
+			wantHunks:   1,
+		},
+		{
+			name:        "remove the only line",
+			oldText:     "a",
+			newText:     "",
+			wantUnified: "--- old\n+++ new\n@@ -1,1 +0,0 @@\n-a\n",
+			wantHunks:   1,
+		},
+		{
+			name:         "replace with context",
+			oldText:      "a\nb\nc",
+			newText:      "a\nx\nc",
+			contextLines: 1,
+			wantUnified:  "--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+x\n c\n",
+			wantHunks:    1,
+		},
+		{
+			name:         "insert without context",
+			oldText:      "a\nc",
+			newText:      "a\nb\nc",
+			contextLines: 0,
+			wantUnified:  "--- old\n+++ new\n@@ -1,0 +2,1 @@\n+b\n",
+			wantHunks:    1,
+		},
+		{
+			name:         "changes far apart are separate hunks",
+			oldText:      "a\nb\nc\nd\ne",
+			newText:      "a\nB\nc\nd\nE",
+			contextLines: 0,
+			wantUnified:  "--- old\n+++ new\n@@ -2,1 +2,1 @@\n-b\n+B\n@@ -5,1 +5,1 @@\n-e\n+E\n",
+			wantHunks:    2,
+		},
+		{
+			name:         "changes close together are one hunk",
+			oldText:      "a\nb\nc\nd\ne",
+			newText:      "a\nB\nc\nd\nE",
+			contextLines: 1,
+			wantUnified:  "--- old\n+++ new\n@@ -1,5 +1,5 @@\n a\n-b\n+B\n c\n d\n-e\n+E\n",
+			wantHunks:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := Compare("old", "new", tt.oldText, tt.newText, tt.contextLines)
+			if diff.Unified != tt.wantUnified {
+				t.Fatalf("unified:\n%q\nwant:\n%q", diff.Unified, tt.wantUnified)
+			}
+			if len(diff.Hunks) != tt.wantHunks {
+				t.Fatalf("got %d hunks, want %d", len(diff.Hunks), tt.wantHunks)
+			}
+		})
+	}
+}
+
+func TestCompareLineNumbersAndWords(t *testing.T) {
+	diff := Compare("old", "new", "a\nhello world\nc", "a\nhello go\nc", 1)
+	if len(diff.Hunks) != 1 {
+		t.Fatalf("got %d hunks, want 1", len(diff.Hunks))
+	}
+
+	hunk := diff.Hunks[0]
+	if hunk.OldStart != 1 || hunk.OldLines != 3 || hunk.NewStart != 1 || hunk.NewLines != 3 {
+		t.Fatalf("got hunk range -%d,%d +%d,%d, want -1,3 +1,3", hunk.OldStart, hunk.OldLines, hunk.NewStart, hunk.NewLines)
+	}
+
+	wantLines := []struct {
+		lineType  OpType
+		oldNumber int
+		newNumber int
+	}{
+		{OpEqual, 1, 1},
+		{OpDelete, 2, 0},
+		{OpInsert, 0, 2},
+		{OpEqual, 3, 3},
+	}
+	if len(hunk.Lines) != len(wantLines) {
+		t.Fatalf("got %d lines, want %d", len(hunk.Lines), len(wantLines))
+	}
+	for i, want := range wantLines {
+		line := hunk.Lines[i]
+		if line.Type != want.lineType || line.OldNumber != want.oldNumber || line.NewNumber != want.newNumber {
+			t.Fatalf("line %d: got %s -%d +%d, want %s -%d +%d", i, line.Type, line.OldNumber, line.NewNumber, want.lineType, want.oldNumber, want.newNumber)
+		}
+	}
+
+	wantOldWords := []*Segment{{Type: OpEqual, Text: "hello "}, {Type: OpDelete, Text: "world"}}
+	wantNewWords := []*Segment{{Type: OpEqual, Text: "hello "}, {Type: OpInsert, Text: "go"}}
+	if !reflect.DeepEqual(hunk.Lines[1].Words, wantOldWords) {
+		t.Fatalf("deleted line words: got %+v, want %+v", hunk.Lines[1].Words, wantOldWords)
+	}
+	if !reflect.DeepEqual(hunk.Lines[2].Words, wantNewWords) {
+		t.Fatalf("inserted line words: got %+v, want %+v", hunk.Lines[2].Words, wantNewWords)
+	}
+	if hunk.Lines[0].Words != nil || hunk.Lines[3].Words != nil {
+		t.Fatal("unchanged lines must not have words")
+	}
+}
+
+func TestMerge3(t *testing.T) {
+	tests := []struct {
+		name          string
+		base          string
+		ours          string
+		theirs        string
+		wantText      string
+		wantConflicts []*Conflict
+	}{
+		{
+			name:     "no changes",
+			base:     "a\nb\nc",
+			ours:     "a\nb\nc",
+			theirs:   "a\nb\nc",
+			wantText: "a\nb\nc",
+		},
+		{
+			name:     "only ours changed",
+			base:     "a\nb\nc",
+			ours:     "a\nB\nc",
+			theirs:   "a\nb\nc",
+			wantText: "a\nB\nc",
+		},
+		{
+			name:     "only theirs changed",
+			base:     "a\nb\nc",
+			ours:     "a\nb\nc",
+			theirs:   "a\nb\nC",
+			wantText: "a\nb\nC",
+		},
+		{
+			name:     "different lines changed",
+			base:     "a\nb\nc\nd",
+			ours:     "A\nb\nc\nd",
+			theirs:   "a\nb\nc\nD",
+			wantText: "A\nb\nc\nD",
+		},
+		{
+			name:     "same change on both sides",
+			base:     "a\nb\nc",
+			ours:     "a\nx\nc",
+			theirs:   "a\nx\nc",
+			wantText: "a\nx\nc",
+		},
+		{
+			name:     "insert and delete",
+			base:     "a\nb\nc",
+			ours:     "a\nb\nnew\nc",
+			theirs:   "b\nc",
+			wantText: "b\nnew\nc",
+		},
+		{
+			name:     "conflicting change",
+			base:     "a\nb\nc",
+			ours:     "a\nX\nc",
+			theirs:   "a\nY\nc",
+			wantText: "a\n<<<<<<< ours\nX\n||||||| base\nb\n=======\nY\n>>>>>>> theirs\nc",
+			wantConflicts: []*Conflict{
+				{StartLine: 2, EndLine: 8, Base: []string{"b"}, Ours: []string{"X"}, Theirs: []string{"Y"}},
+			},
+		},
+		{
+			name:     "conflicting additions to empty base",
+			base:     "",
+			ours:     "x",
+			theirs:   "y",
+			wantText: "<<<<<<< ours\nx\n||||||| base\n=======\ny\n>>>>>>> theirs",
+			wantConflicts: []*Conflict{
+				{StartLine: 1, EndLine: 6, Base: []string{}, Ours: []string{"x"}, Theirs: []string{"y"}},
+			},
+		},
+		{
+			name:     "conflict and clean change",
+			base:     "a\nb\nc\nd\ne",
+			ours:     "A\nb\nc\nX\ne",
+			theirs:   "a\nb\nc\nY\ne",
+			wantText: "A\nb\nc\n<<<<<<< ours\nX\n||||||| base\nd\n=======\nY\n>>>>>>> theirs\ne",
+			wantConflicts: []*Conflict{
+				{StartLine: 4, EndLine: 10, Base: []string{"d"}, Ours: []string{"X"}, Theirs: []string{"Y"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Merge3(SplitLines(tt.base), SplitLines(tt.ours), SplitLines(tt.theirs), "base", "ours", "theirs")
+			if result.Text() != tt.wantText {
+				t.Fatalf("merged:\n%s\nwant:\n%s", result.Text(), tt.wantText)
+			}
+
+			wantConflicts := tt.wantConflicts
+			if wantConflicts == nil {
+				wantConflicts = []*Conflict{}
+			}
+			if !reflect.DeepEqual(result.Conflicts, wantConflicts) {
+				t.Fatalf("conflicts: got %+v, want %+v", result.Conflicts, wantConflicts)
+			}
+			if result.HasConflicts() != (len(wantConflicts) > 0) {
+				t.Fatalf("has conflicts: got %v", result.HasConflicts())
+			}
+
+			// the conflict lines point at the markers of the merged result
+			for _, c := range result.Conflicts {
+				if !strings.HasPrefix(result.Lines[c.StartLine-1], conflictMarkerOurs) || !strings.HasPrefix(result.Lines[c.EndLine-1], conflictMarkerTheirs) {
+					t.Fatalf("conflict %d-%d does not start and end at the markers", c.StartLine, c.EndLine)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "", want: []string{}},
+		{text: "hello", want: []string{"hello"}},
+		{text: "hello  world ", want: []string{"hello", "  ", "world", " "}},
+		{text: " a\tb", want: []string{" ", "a", "\t", "b"}},
+	}
+
+	for _, tt := range tests {
+		got := SplitWords(tt.text)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("SplitWords(%q): got %q, want %q", tt.text, got, tt.want)
+		}
+		if strings.Join(got, "") != tt.text {
+			t.Fatalf("SplitWords(%q) does not join back to the text", tt.text)
+		}
+	}
+}
@@ -8,11 +8,13 @@ import (
 
 const (
 	Error   = "error"
+	Details = "details"
 )
 
 type CustomError struct {
 	ErrorType     error
 	OriginalError error
+	Details       any // optional, structured details returned to the client
 }
 
 func NewCustomError(errorType error, originalError error) *CustomError {
@@ -22,6 +24,14 @@ func NewCustomError(errorType error, originalError error) *CustomError {
 	}
 }
 
+func NewCustomErrorWithDetails(errorType error, originalError error, details any) *CustomError {
+	return &CustomError{
+		ErrorType:     errorType,
+		OriginalError: originalError,
+		Details:       details,
+	}
+}
+
 func GetErrorType(err error) error {
 	if e, ok := err.(*CustomError); ok {
 		return e.ErrorType
@@ -37,6 +47,13 @@ func GetOriginalError(err error) error {
 	return err
 }
 
+func GetErrorDetails(err error) any {
+	if e, ok := err.(*CustomError); ok {
+		return e.Details
+	}
+	return nil
+}
+
 func (c *CustomError) Error() string {
 	return fmt.Sprintf("%s: %s", c.ErrorType.Error(), c.OriginalError.Error())
 }
@@ -44,6 +61,8 @@ func (c *CustomError) Error() string {
 var (
 	ErrBadRequest   = errors.New("bad request")
 	ErrUnauthorized = errors.New("unauthorized")
+	ErrConflict     = errors.New("conflict")
+	ErrForbidden    = errors.New("forbidden")
 )
 
 func CombineHTTPErrorMessage(httpStatusCode int, err error) string {
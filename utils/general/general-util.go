@@ -13,6 +13,7 @@ func SanitizeDuplicateSerials(serials []string) []string {
 		}
 
 		sanitizeSerials = append(sanitizeSerials, serial)
+		serialMap[serial]++
 	}
 
 	return sanitizeSerials
@@ -0,0 +1,227 @@
+package jwkutil
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	keyFileExt = ".pem"
+)
+
+// Key is a token signing key identified by kid, keys without private key are only used to verify
+type Key struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.PrivateKey
+	PublicKey  crypto.PublicKey
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // OKP curve
+	X   string `json:"x,omitempty"`   // OKP public key
+}
+
+type JWKS struct {
+	Keys []*JWK `json:"keys"`
+}
+
+// KeySet holds the keys loaded from a directory, every <kid>.pem file is a key.
+// It can be reloaded while in use, so keys can be rotated without restart
+type KeySet struct {
+	dir          string
+	signingKeyID string
+
+	mu         sync.RWMutex
+	keys       map[string]*Key
+	signingKey *Key
+}
+
+// NewKeySet loads the keys of the directory, the signing key is the given kid,
+// or the greatest kid of the private keys when empty (e.g. dated kids 2025-01, 2025-06)
+func NewKeySet(dir, signingKeyID string) (*KeySet, error) {
+	s := &KeySet{
+		dir:          dir,
+		signingKeyID: signingKeyID,
+	}
+
+	err := s.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Reload reads the keys of the directory again, the current keys are kept when it fails
+func (s *KeySet) Reload() error {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*"+keyFileExt))
+	if err != nil {
+		return fmt.Errorf("error load token keys: %s", err.Error())
+	}
+
+	keys := make(map[string]*Key)
+	kids := []string{}
+	for _, path := range paths {
+		key, err := loadKey(path)
+		if err != nil {
+			return err
+		}
+		keys[key.ID] = key
+		if key.PrivateKey != nil {
+			kids = append(kids, key.ID)
+		}
+	}
+
+	signingKeyID := s.signingKeyID
+	if signingKeyID == "" && len(kids) > 0 {
+		sort.Strings(kids)
+		signingKeyID = kids[len(kids)-1]
+	}
+	signingKey, ok := keys[signingKeyID]
+	if !ok || signingKey.PrivateKey == nil {
+		return fmt.Errorf("error load token keys: private key '%s' is not found in '%s'", signingKeyID, s.dir)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+	s.signingKey = signingKey
+
+	return nil
+}
+
+func (s *KeySet) SigningKey() *Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.signingKey
+}
+
+func (s *KeySet) Key(kid string) (*Key, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// Algorithms returns the signing algorithms of all keys
+func (s *KeySet) Algorithms() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	mapAlg := make(map[string]bool)
+	algs := []string{}
+	for _, key := range s.keys {
+		if !mapAlg[key.Method.Alg()] {
+			mapAlg[key.Method.Alg()] = true
+			algs = append(algs, key.Method.Alg())
+		}
+	}
+
+	return algs
+}
+
+// JWKS returns the public part of all keys, sorted by kid
+func (s *KeySet) JWKS() *JWKS {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jwks := &JWKS{Keys: []*JWK{}}
+	for _, key := range s.keys {
+		jwks.Keys = append(jwks.Keys, key.JWK())
+	}
+	sort.Slice(jwks.Keys, func(i, j int) bool {
+		return jwks.Keys[i].Kid < jwks.Keys[j].Kid
+	})
+
+	return jwks
+}
+
+func (k *Key) JWK() *JWK {
+	jwk := &JWK{
+		Kid: k.ID,
+		Use: "sig",
+		Alg: k.Method.Alg(),
+	}
+
+	switch publicKey := k.PublicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+	}
+
+	return jwk
+}
+
+// load a PEM private or public key, RSA keys sign with RS256 and Ed25519 keys with EdDSA
+func loadKey(path string) (*Key, error) {
+	kid := strings.TrimSuffix(filepath.Base(path), keyFileExt)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error load token key '%s': %s", kid, err.Error())
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("error load token key '%s': file is not PEM encoded", kid)
+	}
+
+	var parsedKey any
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsedKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsedKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsedKey, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsedKey, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		err = fmt.Errorf("PEM type '%s' is not supported", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error load token key '%s': %s", kid, err.Error())
+	}
+
+	key := &Key{ID: kid}
+	switch k := parsedKey.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.PrivateKey, key.PublicKey = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.PublicKey = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.PrivateKey, key.PublicKey = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.PublicKey = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, errors.New("error load token key '" + kid + "': only RSA and Ed25519 keys are supported")
+	}
+
+	return key, nil
+}
@@ -15,3 +15,8 @@ func GenerateId(prefix string) (string, error) {
 	}
 	return prefix + "-" + id, nil
 }
+
+// GenerateRandomId generates a url-safe random id, used for token ids and opaque tokens
+func GenerateRandomId(length int) (string, error) {
+	return gonanoid.New(length)
+}